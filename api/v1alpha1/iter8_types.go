@@ -19,8 +19,11 @@ package v1alpha1
 import (
 	"strings"
 
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -39,6 +42,86 @@ type Iter8Spec struct {
 	AnalyticsEngine AnalyticsEngineSpec `json:"analyticsEngine"`
 	// Metrics is list of system defined metrics
 	Metrics MetricsSpec `json:"metrics"`
+	// Uninstall describes what happens to experiments when the Iter8 resource is deleted
+	// +optional
+	Uninstall *UninstallSpec `json:"uninstall,omitempty"`
+	// Adopt enables discovery of an existing iter8 installation not created by the operator, for example
+	// using kustomize or Helm. Differences from the desired state are reported in status. The operator takes
+	// ownership of the existing resources once the Iter8 resource is annotated iter8.tools/confirm-adoption=true.
+	// +optional
+	Adopt *bool `json:"adopt,omitempty"`
+	// Scope is the scope of the permissions granted to the iter8 controller. With scope cluster, the
+	// controller is granted a ClusterRole. With scope namespaces, the controller is granted a Role in its own
+	// namespace and in each of Namespaces, and only watches those namespaces. Roles can't grant access to
+	// cluster-scoped resources, so the rules for mutating and validating webhook configurations are omitted
+	// from them. An Iter8 resource with scope cluster can't coexist with Iter8 resources in other namespaces;
+	// the newer ones are ignored. Until namespaces are onboarded, the controller watches only its own namespace. Defaults to cluster.
+	// +optional
+	//+kubebuilder:validation:Enum={cluster,namespaces}
+	Scope *string `json:"scope,omitempty"`
+	// Namespaces watched by the iter8 controller when Scope is namespaces
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+	// ExperimentNamespaceSelector selects namespaces to onboard in addition to Namespaces, for example
+	// those labeled iter8.tools/enabled=true. The iter8 controller is granted a Role in each onboarded
+	// namespace in either scope. When Scope is namespaces, it also watches only the onboarded namespaces.
+	// +optional
+	ExperimentNamespaceSelector *metav1.LabelSelector `json:"experimentNamespaceSelector,omitempty"`
+	// IstioInjection, if true, labels onboarded namespaces for Istio sidecar injection.
+	// The label is not removed when a namespace is no longer onboarded. Defaults to false.
+	// +optional
+	IstioInjection *bool `json:"istioInjection,omitempty"`
+	// ExperimentRoles, if true, creates the iter8-experiment-editor and iter8-experiment-viewer ClusterRoles.
+	// They aggregate into the built-in admin, edit and view ClusterRoles so that users granted those roles
+	// in a namespace can manage or view experiments there. Defaults to true.
+	// +optional
+	ExperimentRoles *bool `json:"experimentRoles,omitempty"`
+	// ImageRegistry, if set, replaces the registry of the controller and analytics images not rewritten
+	// by ImageRegistryRewrites, for example with a mirror in an air-gapped cluster
+	// +optional
+	ImageRegistry *string `json:"imageRegistry,omitempty"`
+	// ImageRegistryRewrites maps image prefixes, for example docker.io/iter8, to their replacements.
+	// The longest matching prefix is used.
+	// +optional
+	ImageRegistryRewrites map[string]string `json:"imageRegistryRewrites,omitempty"`
+	// PinImageDigests, if true, refers to images by the digests recorded in status once they are known
+	// so that pods restarted later run the same images. Defaults to false.
+	// +optional
+	PinImageDigests *bool `json:"pinImageDigests,omitempty"`
+	// HA, if true, runs two replicas of each component unless ReplicaCount is specified. Components with more
+	// than one replica are spread across nodes and zones, protected by a PodDisruptionBudget and, for the
+	// controller, use leader election. Defaults to false.
+	// +optional
+	HA *bool `json:"ha,omitempty"`
+	// CommonLabels are added to all resources managed by the operator for this instance. They are not
+	// added to the resources shared by all instances: the experiments CRD and the experiment ClusterRoles.
+	// +optional
+	CommonLabels map[string]string `json:"commonLabels,omitempty"`
+	// CommonAnnotations are added to all resources managed by the operator for this instance, except those
+	// shared by all instances
+	// +optional
+	CommonAnnotations map[string]string `json:"commonAnnotations,omitempty"`
+	// PodAnnotations are added to the pods of iter8 components
+	// +optional
+	PodAnnotations map[string]string `json:"podAnnotations,omitempty"`
+	// Overrides patch the objects generated for iter8 components before they are applied, for settings
+	// not otherwise modeled. Overrides that cannot be applied are reported in status.
+	// +optional
+	Overrides []OverrideSpec `json:"overrides,omitempty"`
+}
+
+// OverrideSpec is a patch of an object generated for an iter8 component
+type OverrideSpec struct {
+	// Kind of the object, for example Deployment
+	Kind string `json:"kind"`
+	// Name of the object
+	Name string `json:"name"`
+	// Type of patch. Defaults to strategic.
+	// +optional
+	//+kubebuilder:validation:Enum={strategic,merge,json}
+	Type *string `json:"type,omitempty"`
+	// Patch in YAML or JSON: a strategic merge patch, a JSON merge patch (RFC 7386) or a JSON patch (RFC 6902)
+	Patch string `json:"patch"`
 }
 
 // Iter8Status defines the observed state of Iter8
@@ -46,8 +129,154 @@ type Iter8Status struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "operator-sdk generate k8s" to regenerate code after modifying this file
 	// Add custom validation using kubebuilder tags: https://book-v1.book.kubebuilder.io/beyond_basics/generating_crd.html
+
+	// Conditions describe the observed state of the installation
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+
+	// Teardown records the progress of removing cluster-scoped resources when the Iter8 resource is deleted
+	// +optional
+	Teardown []TeardownStepStatus `json:"teardown,omitempty"`
+
+	// Adoption lists existing resources not created by the operator that are candidates for adoption
+	// +optional
+	Adoption []AdoptionResourceStatus `json:"adoption,omitempty"`
+
+	// OnboardedNamespaces lists the namespaces listed in Namespaces or selected by ExperimentNamespaceSelector
+	// +optional
+	OnboardedNamespaces []string `json:"onboardedNamespaces,omitempty"`
+
+	// Images lists the images of the iter8 components and the digests they resolved to
+	// +optional
+	Images []ImageStatus `json:"images,omitempty"`
+
+	// Components reports the readiness of the iter8 components
+	// +optional
+	Components []ComponentStatus `json:"components,omitempty"`
+}
+
+// ComponentStatus reports the readiness of an iter8 component
+type ComponentStatus struct {
+	// Name of the component
+	Name string `json:"name"`
+	// Ready is true when all replicas of the component are ready
+	Ready bool `json:"ready"`
+	// Replicas is the desired number of replicas
+	Replicas int32 `json:"replicas"`
+	// ReadyReplicas is the number of replicas passing their readiness probe
+	ReadyReplicas int32 `json:"readyReplicas"`
+}
+
+// ImageStatus records the image of an iter8 component
+type ImageStatus struct {
+	// Component is the name of the component
+	Component string `json:"component"`
+	// Image is the image, after registry rewrites, referred to by tag
+	Image string `json:"image"`
+	// Digest is the digest of the image pulled for the component, if known
+	// +optional
+	Digest string `json:"digest,omitempty"`
+}
+
+// AdoptionResourceStatus describes an existing resource not created by the operator
+type AdoptionResourceStatus struct {
+	// Kind of the resource
+	Kind string `json:"kind"`
+	// Name of the resource
+	Name string `json:"name"`
+	// Namespace of the resource; empty for cluster-scoped resources
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// Differences between the existing resource and the desired state
+	// +optional
+	Differences []string `json:"differences,omitempty"`
+	// Adopted is true once the operator has taken ownership of the resource
+	// +optional
+	Adopted bool `json:"adopted,omitempty"`
+	// ControlledBy identifies another controller of the resource, as kind/name; such a resource is not adopted
+	// +optional
+	ControlledBy string `json:"controlledBy,omitempty"`
+}
+
+// TeardownStepStatus records the progress of removing one cluster-scoped resource
+type TeardownStepStatus struct {
+	// Kind of the resource
+	Kind string `json:"kind"`
+	// Name of the resource
+	Name string `json:"name"`
+	// Removed is true once the resource has been deleted or confirmed absent
+	Removed bool `json:"removed"`
+	// Retained is true if the resource was left in place because it was not created by this Iter8 resource
+	// +optional
+	Retained bool `json:"retained,omitempty"`
+	// Attempts is the number of failed attempts to remove the resource
+	// +optional
+	Attempts int32 `json:"attempts,omitempty"`
+	// LastAttemptTime is the time of the last failed attempt
+	// +optional
+	LastAttemptTime *metav1.Time `json:"lastAttemptTime,omitempty"`
+	// LastError is the error returned by the last failed attempt
+	// +optional
+	LastError string `json:"lastError,omitempty"`
 }
 
+const (
+	// Iter8ConditionMetricsValid indicates whether the metrics defined in the Iter8 resource are valid
+	Iter8ConditionMetricsValid ConditionType = "MetricsValid"
+
+	// Iter8ConditionUninstallBlocked indicates that deletion of the Iter8 resource is blocked
+	Iter8ConditionUninstallBlocked ConditionType = "UninstallBlocked"
+
+	// Iter8ConditionAdoptionPending indicates that existing resources await confirmation before being adopted
+	Iter8ConditionAdoptionPending ConditionType = "AdoptionPending"
+
+	// Iter8ConditionDuplicate indicates that another Iter8 resource already manages iter8 in the namespace, or
+	// would watch the same experiments
+	Iter8ConditionDuplicate ConditionType = "Duplicate"
+
+	// Iter8ConditionReady indicates whether all iter8 components are ready
+	Iter8ConditionReady ConditionType = "Ready"
+
+	// Iter8ConditionRBACInSync indicates whether the RBAC resources granted to the iter8 controller matched the desired state
+	Iter8ConditionRBACInSync ConditionType = "RBACInSync"
+
+	// Iter8ConditionOverridesApplied indicates whether all overrides were applied to the generated objects
+	Iter8ConditionOverridesApplied ConditionType = "OverridesApplied"
+
+	// Iter8ReasonMetricsValid is used when all metrics are valid
+	Iter8ReasonMetricsValid = "MetricsValid"
+	// Iter8ReasonInvalidMetrics is used when one or more metrics are not valid
+	Iter8ReasonInvalidMetrics = "InvalidMetrics"
+	// Iter8ReasonActiveExperiments is used when uninstall is blocked because experiments are active
+	Iter8ReasonActiveExperiments = "ActiveExperiments"
+	// Iter8ReasonAwaitingConfirmation is used when adoption awaits confirmation
+	Iter8ReasonAwaitingConfirmation = "AwaitingConfirmation"
+	// Iter8ReasonAdopted is used when existing resources have been adopted
+	Iter8ReasonAdopted = "Adopted"
+	// Iter8ReasonControlledByOther is used when existing resources can't be adopted because another
+	// resource controls them
+	Iter8ReasonControlledByOther = "ControlledByOther"
+	// Iter8ReasonDuplicateInstance is used when the Iter8 resource is ignored in favor of an older one
+	Iter8ReasonDuplicateInstance = "DuplicateInstance"
+	// Iter8ReasonOverlappingScope is used when the Iter8 resource is ignored in favor of an older one in another
+	// namespace because either has scope cluster
+	Iter8ReasonOverlappingScope = "OverlappingScope"
+	// Iter8ReasonComponentsReady is used when all components are ready
+	Iter8ReasonComponentsReady = "ComponentsReady"
+	// Iter8ReasonComponentsNotReady is used when one or more components are not ready
+	Iter8ReasonComponentsNotReady = "ComponentsNotReady"
+	// Iter8ReasonRBACInSync is used when the RBAC resources match the desired state
+	Iter8ReasonRBACInSync = "InSync"
+	// Iter8ReasonRBACDriftCorrected is used when RBAC resources that differed from the desired state were updated
+	Iter8ReasonRBACDriftCorrected = "DriftCorrected"
+	// Iter8ReasonRBACUpdateFailed is used when RBAC resources could not be created or updated
+	Iter8ReasonRBACUpdateFailed = "UpdateFailed"
+	// Iter8ReasonOverridesApplied is used when all overrides were applied
+	Iter8ReasonOverridesApplied = "OverridesApplied"
+	// Iter8ReasonOverrideFailed is used when one or more overrides could not be applied or matched no object
+	Iter8ReasonOverrideFailed = "OverrideFailed"
+)
+
 // ControllerSpec describes the deployment of the iter8 controller
 type ControllerSpec struct {
 	// Service details of service
@@ -66,12 +295,88 @@ type AnalyticsEngineSpec struct {
 	// MetricsBackends list of metrics backends. Default is single prometheus service with basic authentication in a default location.
 	// +optional
 	MetricsBackend *MetricsBackendSpec `json:"metricsBackend,omitempty"`
+	// Autoscaling, if specified, scales the analytics engine with a HorizontalPodAutoscaler instead of
+	// using Deployment.ReplicaCount
+	// +optional
+	Autoscaling *AutoscalingSpec `json:"autoscaling,omitempty"`
+}
+
+// AutoscalingSpec describes the HorizontalPodAutoscaler of a component
+type AutoscalingSpec struct {
+	// MinReplicas is the lower limit of the number of replicas. Defaults to 1.
+	// +optional
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+	// MaxReplicas is the upper limit of the number of replicas
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas int32 `json:"maxReplicas"`
+	// TargetCPUUtilizationPercentage is the target average CPU utilization, as a percentage of the requested CPU.
+	// Defaults to 80 if no Metrics are specified.
+	// +optional
+	TargetCPUUtilizationPercentage *int32 `json:"targetCPUUtilizationPercentage,omitempty"`
+	// Metrics are additional metrics, for example custom metrics, used to determine the number of replicas
+	// +optional
+	Metrics []autoscalingv2beta2.MetricSpec `json:"metrics,omitempty"`
 }
 
 // ServiceSpec describes the service to be deployed
 type ServiceSpec struct {
 	// Port on which service will listen, default is 8080
 	Port *int32 `json:"port,omitempty"`
+	// Type of service. Defaults to ClusterIP.
+	// +optional
+	//+kubebuilder:validation:Enum={ClusterIP,NodePort,LoadBalancer}
+	Type *corev1.ServiceType `json:"type,omitempty"`
+	// Annotations of the service, for example to configure a load balancer
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// PortName is the name of the service port. Defaults to https for the controller and http for the analytics engine.
+	// +optional
+	PortName *string `json:"portName,omitempty"`
+	// TargetPort is the container port, by number or name, to which the service forwards.
+	// Defaults to the service port.
+	// +optional
+	TargetPort *intstr.IntOrString `json:"targetPort,omitempty"`
+	// NodePort is the port on each node when the type is NodePort or LoadBalancer. Allocated if not specified.
+	// +optional
+	NodePort *int32 `json:"nodePort,omitempty"`
+	// Ingress, if specified, exposes the service outside the cluster with an Ingress
+	// +optional
+	Ingress *IngressSpec `json:"ingress,omitempty"`
+	// Route, if specified, exposes the service outside the cluster with an OpenShift Route
+	// +optional
+	Route *RouteSpec `json:"route,omitempty"`
+}
+
+// IngressSpec describes an Ingress for a service
+type IngressSpec struct {
+	// Host is the fully qualified domain name at which the service is exposed
+	Host string `json:"host"`
+	// Path at which the service is exposed. Defaults to /.
+	// +optional
+	Path *string `json:"path,omitempty"`
+	// IngressClassName is the class of the Ingress
+	// +optional
+	IngressClassName *string `json:"ingressClassName,omitempty"`
+	// Annotations of the Ingress, for example to configure the ingress controller
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// TLS configuration of the Ingress
+	// +optional
+	TLS []networkingv1beta1.IngressTLS `json:"tls,omitempty"`
+}
+
+// RouteSpec describes an OpenShift Route for a service
+type RouteSpec struct {
+	// Host of the Route. Generated by OpenShift if not specified.
+	// +optional
+	Host *string `json:"host,omitempty"`
+	// Termination of TLS by the Route. The Route is not secured if not specified.
+	// +optional
+	//+kubebuilder:validation:Enum={edge,passthrough,reencrypt}
+	Termination *string `json:"termination,omitempty"`
+	// Annotations of the Route
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
 }
 
 // DeploymentSpec describes the deployment of the service
@@ -91,6 +396,89 @@ type DeploymentSpec struct {
 	// https://kubernetes.io/docs/reference/generated/kubernetes-api/v1.17/#resourcerequirements-v1-core
 	// +optional
 	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+	// NodeSelector constrains the pods to nodes with matching labels
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Tolerations of the pods
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// Affinity scheduling constraints of the pods
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+	// TopologySpreadConstraints describe how the pods are spread across topology domains
+	// +optional
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+	// PriorityClassName is the priority class of the pods
+	// +optional
+	PriorityClassName *string `json:"priorityClassName,omitempty"`
+	// ImagePullSecrets used to pull the image. For the controller, they are also added to its ServiceAccount.
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+	// LivenessProbe replaces the default liveness probe of the container
+	// +optional
+	LivenessProbe *corev1.Probe `json:"livenessProbe,omitempty"`
+	// ReadinessProbe replaces the default readiness probe of the container
+	// +optional
+	ReadinessProbe *corev1.Probe `json:"readinessProbe,omitempty"`
+	// StartupProbe replaces the default startup probe of the container
+	// +optional
+	StartupProbe *corev1.Probe `json:"startupProbe,omitempty"`
+	// SecurityContext replaces the default security context of the container, which runs it as non-root
+	// without privilege escalation or capabilities and with a read-only root filesystem
+	// +optional
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty"`
+	// PodSecurityContext replaces the default security context of the pods, which runs them as non-root.
+	// The RuntimeDefault seccomp profile is set with the seccomp.security.alpha.kubernetes.io/pod annotation
+	// rather than the seccompProfile field, which this API predates; clusters enforcing the restricted
+	// Pod Security Standard through admission, which checks only the field, reject the pods.
+	// +optional
+	PodSecurityContext *corev1.PodSecurityContext `json:"podSecurityContext,omitempty"`
+	// Env are environment variables of the container. They replace generated variables of the same name.
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+	// EnvFrom are sources of environment variables of the container
+	// +optional
+	EnvFrom []corev1.EnvFromSource `json:"envFrom,omitempty"`
+	// Volumes are added to the pods
+	// +optional
+	Volumes []corev1.Volume `json:"volumes,omitempty"`
+	// VolumeMounts are added to the container
+	// +optional
+	VolumeMounts []corev1.VolumeMount `json:"volumeMounts,omitempty"`
+	// ExtraContainers are added to the pods, for example as sidecars. Their security contexts and volume
+	// mounts are left as specified.
+	// +optional
+	ExtraContainers []corev1.Container `json:"extraContainers,omitempty"`
+	// InitContainers are added to the pods, with their security contexts and volume mounts left as specified
+	// +optional
+	InitContainers []corev1.Container `json:"initContainers,omitempty"`
+}
+
+// UninstallSpec describes how iter8 is removed when the Iter8 resource is deleted
+type UninstallSpec struct {
+	// Policy determines what happens to the experiments CustomResourceDefinition, and so to all experiments.
+	// deleteAll deletes it; retainCRD leaves it and all experiments in place;
+	// blockIfExperimentsExist deletes it only when no experiments are active. Defaults to blockIfExperimentsExist.
+	// +optional
+	//+kubebuilder:validation:Enum={deleteAll,retainCRD,blockIfExperimentsExist}
+	Policy *string `json:"policy,omitempty"`
+	// Archive, if specified, is where all experiments are exported before the CustomResourceDefinition is deleted
+	// +optional
+	Archive *ArchiveSpec `json:"archive,omitempty"`
+}
+
+// ArchiveSpec describes a ConfigMap or Secret to which experiments are exported
+type ArchiveSpec struct {
+	// Kind of the archive. Defaults to ConfigMap.
+	// +optional
+	//+kubebuilder:validation:Enum={ConfigMap,Secret}
+	Kind *string `json:"kind,omitempty"`
+	// Name of the archive. Defaults to iter8-experiments-archive.
+	// +optional
+	Name *string `json:"name,omitempty"`
+	// Namespace of the archive. Defaults to the namespace of the Iter8 resource.
+	// +optional
+	Namespace *string `json:"namespace,omitempty"`
 }
 
 // MetricsBackendSpec describes a backend from which metrics are collected
@@ -122,10 +510,35 @@ type MetricsBackendAuthenticationSpec struct {
 
 // MetricsSpec list of available metrics
 type MetricsSpec struct {
+	// CanonicalUnits are the time units in which all metrics with time units are reported.
+	// When set, query templates of metrics with other time units are rewritten to convert their values.
+	// +optional
+	//+kubebuilder:validation:Enum={msec,sec}
+	CanonicalUnits *string `json:"canonicalUnits,omitempty" yaml:"canonicalUnits,omitempty"`
+	// From is a list of ConfigMaps from which additional metrics are loaded. Each ConfigMap uses the keys
+	// counter_metrics.yaml, ratio_metrics.yaml, histogram_metrics.yaml and gauge_metrics.yaml.
+	// Metrics defined inline take precedence over those loaded from a ConfigMap.
+	// +optional
+	From *[]MetricsSourceSpec `json:"from,omitempty" yaml:"from,omitempty"`
 	// CounterMetrics
 	CounterMetrics *[]CounterMetricSpec `json:"counter,omitempty" yaml:"counter,omitempty"`
 	// RatioMetrics
 	RatioMetrics *[]RatioMetricSpec `json:"ratio,omitempty" yaml:"ratio,omitempty"`
+	// HistogramMetrics
+	HistogramMetrics *[]HistogramMetricSpec `json:"histogram,omitempty" yaml:"histogram,omitempty"`
+	// GaugeMetrics
+	GaugeMetrics *[]GaugeMetricSpec `json:"gauge,omitempty" yaml:"gauge,omitempty"`
+}
+
+// MetricsSourceSpec identifies a ConfigMap from which metrics are loaded
+type MetricsSourceSpec struct {
+	// ConfigMap is the name of the ConfigMap
+	ConfigMap string `json:"configMap" yaml:"configMap"`
+	// Namespace of the ConfigMap. Defaults to the namespace of the Iter8 resource. A ConfigMap in any namespace
+	// may be named; the operator reads it with its own permissions, so creating an Iter8 resource should be
+	// limited to those allowed to read ConfigMaps in every namespace.
+	// +optional
+	Namespace *string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
 }
 
 // CounterMetricSpec defines a counter type metric
@@ -138,7 +551,8 @@ type CounterMetricSpec struct {
 	// +optional
 	//+kubebuilder:validation=Enum{lower,higher}
 	PreferredDirection *string `json:"preferred_direction,omitempty" yaml:"preferred_direction,omitempty"`
-	// Units
+	// Units of the value of the metric, if any
+	// +optional
 	//+kubebuilder:validation:Enum={msec,sec}
 	Units *string `json:"units,omitempty" yaml:"units,omitempty"`
 }
@@ -162,6 +576,53 @@ type RatioMetricSpec struct {
 	// Boolean flag indicating if the value of this metric is always in the range 0 to 1
 	// +optional
 	ZeroToOne *bool `json:"zero_to_one,omitempty" yaml:"zero_to_one,omitempty"`
+
+	// Units of the value of the metric. Derived from the units of the numerator and denominator;
+	// if specified, must match the derived units.
+	// +optional
+	//+kubebuilder:validation:Enum={msec,sec}
+	Units *string `json:"units,omitempty" yaml:"units,omitempty"`
+}
+
+// HistogramMetricSpec defines a metric computed as a quantile of a histogram
+type HistogramMetricSpec struct {
+	// Name
+	Name string `json:"name" yaml:"name"`
+	// BucketMetric is the name of the histogram bucket metric, e.g. istio_request_duration_milliseconds_bucket
+	BucketMetric string `json:"bucket_metric" yaml:"bucket_metric"`
+	// Quantile to compute; a number between 0 and 1, e.g. "0.95"
+	//+kubebuilder:validation:Pattern=`^(0(\.[0-9]+)?|1(\.0+)?)$`
+	Quantile string `json:"quantile" yaml:"quantile"`
+	// Interval over which the rate of the buckets is computed. Defaults to $interval.
+	// +optional
+	Interval *string `json:"interval,omitempty" yaml:"interval,omitempty"`
+	// LabelMatchers is an optional comma separated list of label matchers applied to the bucket metric,
+	// e.g. reporter='source',job='envoy-stats'
+	// +optional
+	LabelMatchers *string `json:"label_matchers,omitempty" yaml:"label_matchers,omitempty"`
+	// PreferredDirection
+	// +optional
+	//+kubebuilder:validation=Enum{lower,higher}
+	PreferredDirection *string `json:"preferred_direction,omitempty" yaml:"preferred_direction,omitempty"`
+	// Units of the value of the metric, if any
+	// +optional
+	//+kubebuilder:validation:Enum={msec,sec}
+	Units *string `json:"units,omitempty" yaml:"units,omitempty"`
+}
+
+// GaugeMetricSpec defines a gauge type metric; its value is the instantaneous value of the query
+type GaugeMetricSpec struct {
+	// Name
+	Name string `json:"name" yaml:"name"`
+	// QueryTemplate
+	QueryTemplate string `json:"query_template" yaml:"query_template"`
+	// PreferredDirection
+	// +optional
+	//+kubebuilder:validation=Enum{lower,higher}
+	PreferredDirection *string `json:"preferred_direction,omitempty" yaml:"preferred_direction,omitempty"`
+	// Units
+	// +optional
+	Units *string `json:"units,omitempty" yaml:"units,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -204,6 +665,43 @@ func GetReplicaCount(deploy DeploymentSpec) int32 {
 	return replicaCount
 }
 
+// GetComponentReplicaCount returns specified replica count or the default, which depends on whether HA is enabled
+func GetComponentReplicaCount(spec Iter8Spec, deploy DeploymentSpec) int32 {
+	if nil == deploy.ReplicaCount && GetHA(spec) {
+		return 2
+	}
+	return GetReplicaCount(deploy)
+}
+
+// Types of override patches
+const (
+	PatchTypeStrategic = "strategic"
+	PatchTypeMerge     = "merge"
+	PatchTypeJSON      = "json"
+)
+
+// GetPatchType returns specified patch type or default
+func GetPatchType(override OverrideSpec) string {
+	defaultValue := PatchTypeStrategic
+
+	value := override.Type
+	if nil == value {
+		return defaultValue
+	}
+	return *value
+}
+
+// GetHA returns whether components are highly available
+func GetHA(spec Iter8Spec) bool {
+	defaultValue := false
+
+	value := spec.HA
+	if nil == value {
+		return defaultValue
+	}
+	return *value
+}
+
 // GetImagePullPolicy returns specified pull policy or default
 func GetImagePullPolicy(deploy DeploymentSpec) corev1.PullPolicy {
 	// Default pull policy is IfNotPresent
@@ -227,6 +725,16 @@ func GetImagePullPolicy(deploy DeploymentSpec) corev1.PullPolicy {
 	return pullPolicy
 }
 
+// GetServiceType returns specified service type or default
+func GetServiceType(svc *ServiceSpec) corev1.ServiceType {
+	defaultValue := corev1.ServiceTypeClusterIP
+
+	if nil == svc || nil == svc.Type {
+		return defaultValue
+	}
+	return *svc.Type
+}
+
 // GetServicePort returns specified replica count or default
 func GetServicePort(svc *ServiceSpec, defaultPort int32) int32 {
 	port := defaultPort
@@ -245,6 +753,133 @@ func GetServicePort(svc *ServiceSpec, defaultPort int32) int32 {
 	return port
 }
 
+// Uninstall policies
+const (
+	UninstallPolicyDeleteAll               = "deleteAll"
+	UninstallPolicyRetainCRD               = "retainCRD"
+	UninstallPolicyBlockIfExperimentsExist = "blockIfExperimentsExist"
+)
+
+// GetUninstallPolicy returns the uninstall policy or the default
+func GetUninstallPolicy(uninstall *UninstallSpec) string {
+	defaultValue := UninstallPolicyBlockIfExperimentsExist
+
+	if nil == uninstall {
+		return defaultValue
+	}
+	value := uninstall.Policy
+	if nil == value {
+		return defaultValue
+	}
+	return *value
+}
+
+// GetArchiveKind returns the kind of the archive or the default
+func GetArchiveKind(archive ArchiveSpec) string {
+	defaultValue := "ConfigMap"
+
+	value := archive.Kind
+	if nil == value {
+		return defaultValue
+	}
+	return *value
+}
+
+// GetArchiveName returns the name of the archive or the default
+func GetArchiveName(archive ArchiveSpec) string {
+	defaultValue := "iter8-experiments-archive"
+
+	value := archive.Name
+	if nil == value {
+		return defaultValue
+	}
+	return *value
+}
+
+// GetArchiveNamespace returns the namespace of the archive or the default
+func GetArchiveNamespace(archive ArchiveSpec, defaultNamespace string) string {
+	value := archive.Namespace
+	if nil == value {
+		return defaultNamespace
+	}
+	return *value
+}
+
+// GetAdopt returns whether adoption of an existing installation is enabled
+func GetAdopt(spec Iter8Spec) bool {
+	defaultValue := false
+
+	value := spec.Adopt
+	if nil == value {
+		return defaultValue
+	}
+	return *value
+}
+
+// Scopes
+const (
+	ScopeCluster    = "cluster"
+	ScopeNamespaces = "namespaces"
+)
+
+// GetScope returns the scope of the iter8 controller or the default
+func GetScope(spec Iter8Spec) string {
+	defaultValue := ScopeCluster
+
+	value := spec.Scope
+	if nil == value {
+		return defaultValue
+	}
+	return *value
+}
+
+// GetIstioInjection returns whether onboarded namespaces are labeled for Istio sidecar injection
+func GetIstioInjection(spec Iter8Spec) bool {
+	defaultValue := false
+
+	value := spec.IstioInjection
+	if nil == value {
+		return defaultValue
+	}
+	return *value
+}
+
+// GetExperimentRoles returns whether the aggregated experiment ClusterRoles are created
+func GetExperimentRoles(spec Iter8Spec) bool {
+	defaultValue := true
+
+	value := spec.ExperimentRoles
+	if nil == value {
+		return defaultValue
+	}
+	return *value
+}
+
+// GetPinImageDigests returns whether images are referred to by digest once the digests are known
+func GetPinImageDigests(spec Iter8Spec) bool {
+	defaultValue := false
+
+	value := spec.PinImageDigests
+	if nil == value {
+		return defaultValue
+	}
+	return *value
+}
+
+// GetTargetCPUUtilizationPercentage returns the target CPU utilization of a HorizontalPodAutoscaler, if any
+func GetTargetCPUUtilizationPercentage(autoscaling AutoscalingSpec) *int32 {
+	defaultValue := int32(80)
+
+	value := autoscaling.TargetCPUUtilizationPercentage
+	if nil == value {
+		if len(autoscaling.Metrics) > 0 {
+			return nil
+		}
+		return &defaultValue
+	}
+	return value
+}
+
 // GetMetricsBackendURL returns url of the metrics backend
 func GetMetricsBackendURL(mbes *MetricsBackendSpec, defaultURL string) *string {
 	if nil == mbes {
@@ -351,9 +986,100 @@ func GetRatioMetrics(metrics MetricsSpec) *[]RatioMetricSpec {
 	return metrics.RatioMetrics
 }
 
-// GetCounterMetricUnits returns units of a counter type metric
+// GetMetricsSources returns the ConfigMaps from which metrics are loaded if any
+func GetMetricsSources(metrics MetricsSpec) *[]MetricsSourceSpec {
+	defaultValue := make([]MetricsSourceSpec, 0)
+
+	if nil == metrics.From {
+		return &defaultValue
+	}
+
+	return metrics.From
+}
+
+// GetMetricsSourceNamespace returns the namespace of a metrics ConfigMap or the default
+func GetMetricsSourceNamespace(source MetricsSourceSpec, defaultNamespace string) string {
+	value := source.Namespace
+	if nil == value {
+		return defaultNamespace
+	}
+
+	return *value
+}
+
+// GetHistogramMetrics returns histogram metrics if any
+func GetHistogramMetrics(metrics MetricsSpec) *[]HistogramMetricSpec {
+	defaultValue := make([]HistogramMetricSpec, 0)
+
+	if nil == metrics.HistogramMetrics {
+		return &defaultValue
+	}
+
+	return metrics.HistogramMetrics
+}
+
+// GetGaugeMetrics returns gauge metrics if any
+func GetGaugeMetrics(metrics MetricsSpec) *[]GaugeMetricSpec {
+	defaultValue := make([]GaugeMetricSpec, 0)
+
+	if nil == metrics.GaugeMetrics {
+		return &defaultValue
+	}
+
+	return metrics.GaugeMetrics
+}
+
+// GetHistogramMetricInterval returns the interval of a histogram type metric
+func GetHistogramMetricInterval(metric HistogramMetricSpec) string {
+	defaultValue := "$interval"
+
+	value := metric.Interval
+	if nil == value {
+		return defaultValue
+	}
+
+	return *value
+}
+
+// GetCanonicalUnits returns the canonical time units of metrics or "" if metrics are not normalized
+func GetCanonicalUnits(metrics MetricsSpec) string {
+	defaultValue := ""
+
+	value := metrics.CanonicalUnits
+	if nil == value {
+		return defaultValue
+	}
+
+	return *value
+}
+
+// GetCounterMetricUnits returns units of a counter type metric or "" if the metric has no units
 func GetCounterMetricUnits(metric CounterMetricSpec) string {
-	defaultValue := "secs"
+	defaultValue := ""
+
+	value := metric.Units
+	if nil == value {
+		return defaultValue
+	}
+
+	return *value
+}
+
+// GetHistogramMetricUnits returns units of a histogram type metric or "" if the metric has no units
+func GetHistogramMetricUnits(metric HistogramMetricSpec) string {
+	defaultValue := ""
+
+	value := metric.Units
+	if nil == value {
+		return defaultValue
+	}
+
+	return *value
+}
+
+// GetGaugeMetricUnits returns units of a gauge type metric or "" if the metric has no units
+func GetGaugeMetricUnits(metric GaugeMetricSpec) string {
+	defaultValue := ""
 
 	value := metric.Units
 	if nil == value {
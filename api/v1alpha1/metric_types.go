@@ -0,0 +1,100 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MetricSpec defines a single metric. Exactly one of the metric types should be specified.
+type MetricSpec struct {
+	// Counter is a counter type metric
+	// +optional
+	Counter *CounterMetricSpec `json:"counter,omitempty"`
+	// Ratio is a ratio type metric
+	// +optional
+	Ratio *RatioMetricSpec `json:"ratio,omitempty"`
+	// Histogram is a histogram quantile type metric
+	// +optional
+	Histogram *HistogramMetricSpec `json:"histogram,omitempty"`
+	// Gauge is a gauge type metric
+	// +optional
+	Gauge *GaugeMetricSpec `json:"gauge,omitempty"`
+}
+
+// MetricStatus defines the observed state of Metric
+type MetricStatus struct {
+	// Conditions describe whether the metric has been accepted
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+const (
+	// MetricConditionAccepted indicates whether the metric was merged into the metrics configuration
+	MetricConditionAccepted ConditionType = "Accepted"
+
+	// MetricReasonMerged is used when the metric was merged into the metrics configuration
+	MetricReasonMerged = "Merged"
+	// MetricReasonInvalid is used when the metric definition is not valid
+	MetricReasonInvalid = "Invalid"
+	// MetricReasonConflict is used when another metric with the same name was already defined
+	MetricReasonConflict = "Conflict"
+)
+
+// Metric is the Schema for the metrics API. A Metric is merged into the metrics of the Iter8 resource
+// in its namespace or that onboards its namespace; if there is more than one, the oldest.
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="accepted",type="string",JSONPath=".status.conditions[?(@.type=='Accepted')].status"
+// +kubebuilder:printcolumn:name="reason",type="string",JSONPath=".status.conditions[?(@.type=='Accepted')].reason"
+type Metric struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MetricSpec   `json:"spec,omitempty"`
+	Status MetricStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// MetricList contains a list of Metric
+type MetricList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Metric `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Metric{}, &MetricList{})
+}
+
+// GetMetricName returns the name of the metric defined by a Metric
+func GetMetricName(metric MetricSpec) string {
+	if nil != metric.Counter {
+		return metric.Counter.Name
+	}
+	if nil != metric.Ratio {
+		return metric.Ratio.Name
+	}
+	if nil != metric.Histogram {
+		return metric.Histogram.Name
+	}
+	if nil != metric.Gauge {
+		return metric.Gauge.Name
+	}
+	return ""
+}
@@ -0,0 +1,303 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensions "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// adoptionConfirmationAnnotation must be set to "true" on the Iter8 resource before existing resources are adopted
+	adoptionConfirmationAnnotation = "iter8.tools/confirm-adoption"
+
+	// fieldManager is the field manager used when the operator takes ownership of existing resources
+	fieldManager = "iter8-operator"
+)
+
+// adoptionCandidate is a resource of an iter8 installation that may already exist
+type adoptionCandidate struct {
+	kind    string
+	desired runtime.Object
+	found   runtime.Object
+}
+
+// adoptionForIter8 discovers existing iter8 resources not created by the operator and reports how they
+// differ from the desired state. Once confirmed by annotation, the operator takes ownership of them.
+func (r *Iter8Reconciler) adoptionForIter8(iter8 *iter8v1alpha1.Iter8) error {
+	r.Log.Info("adoptionForIter8() called")
+	candidates, err := r.adoptionCandidates(iter8)
+	if err != nil {
+		return err
+	}
+
+	confirmed := iter8.GetAnnotations()[adoptionConfirmationAnnotation] == "true"
+	original := iter8.Status.DeepCopy()
+	resources := []iter8v1alpha1.AdoptionResourceStatus{}
+	pending := 0
+	conflicts := 0
+
+	for _, candidate := range candidates {
+		desired, err := meta.Accessor(candidate.desired)
+		if err != nil {
+			return err
+		}
+		err = r.Client.Get(context.TODO(), types.NamespacedName{Name: desired.GetName(), Namespace: desired.GetNamespace()}, candidate.found)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		found, err := meta.Accessor(candidate.found)
+		if err != nil {
+			return err
+		}
+
+		status := iter8v1alpha1.AdoptionResourceStatus{
+			Kind:      candidate.kind,
+			Name:      found.GetName(),
+			Namespace: found.GetNamespace(),
+		}
+		managed := r.isManaged(iter8, found)
+		if isShared(desired) {
+			managed = isManagedShared(found)
+		}
+		if managed {
+			// report resources adopted earlier so that the record is kept
+			if previous := adoptionStatus(original.Adoption, status); nil != previous {
+				resources = append(resources, *previous)
+			}
+			continue
+		}
+
+		status.Differences = differences(candidate.desired, candidate.found)
+		if owner := metav1.GetControllerOf(found); nil != owner && found.GetNamespace() != "" {
+			// a resource has a single controller, so it can't be adopted until released by its owner
+			r.Log.Info("Existing resource controlled by another resource, not adopting", "kind", candidate.kind, "name", found.GetName(), "owner", owner.Name)
+			status.ControlledBy = owner.Kind + "/" + owner.Name
+			conflicts++
+		} else if confirmed {
+			r.Log.Info("Adopting existing resource", "kind", candidate.kind, "name", found.GetName(), "namespace", found.GetNamespace())
+			err = r.adopt(iter8, candidate.found, isShared(desired))
+			if err != nil {
+				return err
+			}
+			status.Adopted = true
+		} else {
+			pending++
+		}
+		resources = append(resources, status)
+	}
+
+	iter8.Status.Adoption = resources
+	condition := iter8v1alpha1.Condition{
+		Type:   iter8v1alpha1.Iter8ConditionAdoptionPending,
+		Status: corev1.ConditionFalse,
+	}
+	if conflicts > 0 {
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = iter8v1alpha1.Iter8ReasonControlledByOther
+		condition.Message = fmt.Sprintf("%d existing resource(s) controlled by another resource can't be adopted; "+
+			"%d existing resource(s) await confirmation", conflicts, pending)
+	} else if pending > 0 {
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = iter8v1alpha1.Iter8ReasonAwaitingConfirmation
+		condition.Message = fmt.Sprintf("%d existing resource(s) not managed by the operator; annotate with %s=true to adopt them",
+			pending, adoptionConfirmationAnnotation)
+	} else if len(resources) > 0 {
+		condition.Reason = iter8v1alpha1.Iter8ReasonAdopted
+		condition.Message = fmt.Sprintf("%d existing resource(s) adopted", len(resources))
+	}
+	iter8v1alpha1.SetCondition(&iter8.Status.Conditions, condition)
+
+	if !equality.Semantic.DeepEqual(original, &iter8.Status) {
+		err = r.Client.Status().Update(context.TODO(), iter8)
+		if err != nil {
+			r.Log.Error(err, "Unable to update Iter8 status")
+		}
+	}
+	return nil
+}
+
+// adoptionCandidates returns the desired state of every resource of an iter8 installation. Building the
+// candidates has no side effects; in particular, the status of Metric resources is not updated.
+func (r *Iter8Reconciler) adoptionCandidates(iter8 *iter8v1alpha1.Iter8) ([]adoptionCandidate, error) {
+	candidates := []adoptionCandidate{
+		{kind: "Deployment", desired: r.deploymentForIter8Controller(iter8), found: &appsv1.Deployment{}},
+		{kind: "Deployment", desired: r.deploymentForIter8Analytics(iter8), found: &appsv1.Deployment{}},
+		{kind: "Service", desired: r.serviceForIter8Controller(iter8), found: &corev1.Service{}},
+		{kind: "Service", desired: r.serviceForAnalytics(iter8), found: &corev1.Service{}},
+		{kind: "ServiceAccount", desired: r.serviceAccountForIter8Controller(iter8), found: &corev1.ServiceAccount{}},
+		{kind: "ConfigMap", desired: r.configConfigMapForAnalytics(iter8), found: &corev1.ConfigMap{}},
+		{kind: "ConfigMap", desired: r.notifierConfigMapForIter8(iter8), found: &corev1.ConfigMap{}},
+	}
+	metrics, _ := r.metricsForIter8(iter8)
+	candidates = append(candidates, adoptionCandidate{kind: "ConfigMap", desired: r.metricsConfigMapForIter8(iter8, metrics), found: &corev1.ConfigMap{}})
+
+	// installations not created by the operator use the default names for cluster-scoped resources; once
+	// adopted, they are replaced by the resources named for the Iter8 instance (see deleteLegacyRBACForIter8)
+	binding := r.clusterRoleBindingForIter8(iter8)
+	binding.Name = roleBindingDefaultName
+	binding.RoleRef.Name = roleDefaultName
+	candidates = append(candidates, adoptionCandidate{kind: "ClusterRoleBinding", desired: binding, found: &rbacv1.ClusterRoleBinding{}})
+
+	role, err := r.clusterRoleForIter8(iter8)
+	if err != nil {
+		return nil, err
+	}
+	role.Name = roleDefaultName
+	candidates = append(candidates, adoptionCandidate{kind: "ClusterRole", desired: role, found: &rbacv1.ClusterRole{}})
+
+	crd, err := r.readCRD()
+	if err != nil {
+		return nil, err
+	}
+	setSharedLabels(crd)
+	candidates = append(candidates, adoptionCandidate{kind: "CustomResourceDefinition", desired: crd, found: &apiextensions.CustomResourceDefinition{}})
+
+	return candidates, nil
+}
+
+// isManaged determines whether a resource is already managed by the operator for iter8. Resources in the
+// namespace of iter8 are owned by it; other resources are labeled with their owner.
+func (r *Iter8Reconciler) isManaged(iter8 *iter8v1alpha1.Iter8, obj metav1.Object) bool {
+	if obj.GetNamespace() != iter8.Namespace {
+		return isOwnedBy(iter8, obj)
+	}
+	return metav1.IsControlledBy(obj, iter8)
+}
+
+// adopt takes ownership of an existing resource. Namespaced resources are owned by the Iter8 resource;
+// cluster-scoped resources are labeled with their owner, or as shared if shared by all Iter8 instances.
+func (r *Iter8Reconciler) adopt(iter8 *iter8v1alpha1.Iter8, obj runtime.Object, shared bool) error {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case shared:
+		setSharedLabels(accessor)
+	case accessor.GetNamespace() == "":
+		setOwnerLabels(iter8, accessor)
+	default:
+		err = controllerutil.SetControllerReference(iter8, accessor, r.Scheme)
+		if err != nil {
+			return err
+		}
+	}
+	labels := accessor.GetLabels()
+	if nil == labels {
+		labels = map[string]string{}
+	}
+	labels[managedByLabel] = managedByValue
+	accessor.SetLabels(labels)
+
+	return r.Client.Update(context.TODO(), obj, client.FieldOwner(fieldManager))
+}
+
+// adoptionStatus returns the previously recorded status of a resource, if any
+func adoptionStatus(resources []iter8v1alpha1.AdoptionResourceStatus, resource iter8v1alpha1.AdoptionResourceStatus) *iter8v1alpha1.AdoptionResourceStatus {
+	for i := range resources {
+		if resources[i].Kind == resource.Kind && resources[i].Name == resource.Name && resources[i].Namespace == resource.Namespace {
+			return &resources[i]
+		}
+	}
+	return nil
+}
+
+// differences describes how an existing resource differs from its desired state
+func differences(desired runtime.Object, found runtime.Object) []string {
+	diffs := []string{}
+	switch d := desired.(type) {
+	case *appsv1.Deployment:
+		f := found.(*appsv1.Deployment)
+		if nil != d.Spec.Replicas && (nil == f.Spec.Replicas || *d.Spec.Replicas != *f.Spec.Replicas) {
+			diffs = append(diffs, fmt.Sprintf("replicas: desired %d", *d.Spec.Replicas))
+		}
+		for _, dc := range d.Spec.Template.Spec.Containers {
+			fc := findContainer(f.Spec.Template.Spec.Containers, dc.Name)
+			if nil == fc {
+				diffs = append(diffs, fmt.Sprintf("container %s: missing", dc.Name))
+				continue
+			}
+			if dc.Image != fc.Image {
+				diffs = append(diffs, fmt.Sprintf("container %s: image %s, desired %s", dc.Name, fc.Image, dc.Image))
+			}
+			if !equality.Semantic.DeepEqual(dc.Resources, fc.Resources) {
+				diffs = append(diffs, fmt.Sprintf("container %s: resources differ", dc.Name))
+			}
+		}
+	case *corev1.Service:
+		f := found.(*corev1.Service)
+		for _, dp := range d.Spec.Ports {
+			if !hasServicePort(f.Spec.Ports, dp.Port) {
+				diffs = append(diffs, fmt.Sprintf("port %d: missing", dp.Port))
+			}
+		}
+	case *corev1.ConfigMap:
+		f := found.(*corev1.ConfigMap)
+		for key, value := range d.Data {
+			if current, ok := f.Data[key]; !ok {
+				diffs = append(diffs, fmt.Sprintf("data %s: missing", key))
+			} else if current != value {
+				diffs = append(diffs, fmt.Sprintf("data %s: differs", key))
+			}
+		}
+	case *rbacv1.ClusterRole:
+		f := found.(*rbacv1.ClusterRole)
+		if !equality.Semantic.DeepEqual(d.Rules, f.Rules) {
+			diffs = append(diffs, "rules differ")
+		}
+	case *rbacv1.ClusterRoleBinding:
+		f := found.(*rbacv1.ClusterRoleBinding)
+		if !equality.Semantic.DeepEqual(d.Subjects, f.Subjects) {
+			diffs = append(diffs, "subjects differ")
+		}
+		if d.RoleRef != f.RoleRef {
+			diffs = append(diffs, "roleRef differs")
+		}
+	case *apiextensions.CustomResourceDefinition:
+		f := found.(*apiextensions.CustomResourceDefinition)
+		if d.Spec.Version != f.Spec.Version {
+			diffs = append(diffs, fmt.Sprintf("version %s, desired %s", f.Spec.Version, d.Spec.Version))
+		}
+		if !equality.Semantic.DeepEqual(d.Spec.Validation, f.Spec.Validation) {
+			diffs = append(diffs, "schema differs")
+		}
+	}
+	return diffs
+}
+
+// utility function finds a container by name
+func findContainer(containers []corev1.Container, name string) *corev1.Container {
+	for i := range containers {
+		if containers[i].Name == name {
+			return &containers[i]
+		}
+	}
+	return nil
+}
+
+// utility function determines if a service exposes a port
+func hasServicePort(ports []corev1.ServicePort, port int32) bool {
+	for _, p := range ports {
+		if p.Port == port {
+			return true
+		}
+	}
+	return false
+}
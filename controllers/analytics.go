@@ -10,6 +10,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
@@ -17,6 +18,8 @@ const (
 	analyticsDefaultName        = "iter8-analytics"
 	analyticsDefaultConfigFile  = "config.yaml"
 	analyticsDefaultServicePort = int32(8080)
+	// analyticsReadinessPath is served by the analytics API once it is ready: its API documentation
+	analyticsReadinessPath = "/"
 
 	metricsBackendAuthType        = "authType"
 	metricsBackendAuthTypeNone    = "none"
@@ -42,9 +45,24 @@ func (r *Iter8Reconciler) analyticsEngineForIter8(iter8 *iter8v1alpha1.Iter8) er
 		r.Log.Error(err, "Failed to create analytics Service")
 		return err
 	}
+	err = r.exposureForService(iter8, r.serviceForAnalytics(iter8), iter8.Spec.AnalyticsEngine.Service)
+	if err != nil {
+		r.Log.Error(err, "Failed to expose analytics Service")
+		return err
+	}
 	err = r.createOrUpdateDeploymentForAnalytics(iter8)
 	if err != nil {
 		r.Log.Error(err, "Failed to create analytics Deployment")
+		return err
+	}
+	err = r.createOrUpdateAutoscalerForAnalytics(iter8)
+	if err != nil {
+		r.Log.Error(err, "Failed to create analytics HorizontalPodAutoscaler")
+		return err
+	}
+	err = r.createOrUpdatePodDisruptionBudget(iter8, analyticsDefaultName, analyticsReplicas(iter8))
+	if err != nil {
+		r.Log.Error(err, "Failed to create analytics PodDisruptionBudget")
 	}
 	return err
 }
@@ -63,7 +81,19 @@ func (r *Iter8Reconciler) createOrUpdateConfigConfigMapForAnalytics(iter8 *iter8
 		return r.Client.Create(context.TODO(), cm)
 	}
 
-	// If changed, update
+	// If changed, update. The data is only updated when overridden; otherwise it is left to users.
+	if r.isOverridden(iter8, cm) && !hasData(found, cm) {
+		r.Log.Info("ConfigMap overridden, updating", "name", cm.Name)
+		found.Data = cm.Data
+		found.BinaryData = cm.BinaryData
+		mergeMetadata(found, cm)
+		return r.Client.Update(context.TODO(), found)
+	}
+	if !hasMetadata(found, cm) {
+		r.Log.Info("ConfigMap labels or annotations changed, updating", "name", cm.Name)
+		mergeMetadata(found, cm)
+		return r.Client.Update(context.TODO(), found)
+	}
 	r.Log.Info("ConfigMap already present", "name", cm.Name)
 	// cm.ResourceVersion = found.GetResourceVersion()
 	// return r.Client.Update(context.TODO(), cm)
@@ -98,11 +128,6 @@ func (r *Iter8Reconciler) getUsernamePassword(iter8 *iter8v1alpha1.Iter8) (strin
 
 func (r *Iter8Reconciler) configConfigMapForAnalytics(iter8 *iter8v1alpha1.Iter8) *corev1.ConfigMap {
 	r.Log.Info("configConfigMapForAnalytics() called")
-	labels := map[string]string{
-		"app.kubernetes.io/name":     analyticsDefaultName,
-		"app.kubernetes.io/instance": "iter8-analytics",
-	}
-
 	port := iter8v1alpha1.GetServicePort(iter8.Spec.AnalyticsEngine.Service, analyticsDefaultServicePort)
 	backendType := analyticsDefaultBackendMetricsType
 
@@ -145,13 +170,15 @@ metricsBackend:
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      analyticsDefaultName,
 			Namespace: iter8.Namespace,
-			Labels:    labels,
 		},
 		Data: map[string]string{
 			"config.yaml": config,
 		},
 	}
 
+	setMetadata(iter8, cm, labelsForComponent(iter8, analyticsDefaultName))
+	r.override(iter8, cm)
+
 	// Set Iter8 instance as the owner and controller
 	controllerutil.SetControllerReference(iter8, cm, r.Scheme)
 	return cm
@@ -161,21 +188,7 @@ func (r *Iter8Reconciler) createOrUpdateServiceForAnalytics(iter8 *iter8v1alpha1
 	// Desired state
 	service := r.serviceForAnalytics(iter8)
 
-	// Get current state
-	found := &corev1.Service{}
-	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: service.Name, Namespace: iter8.Namespace}, found)
-	if err != nil {
-		r.Log.Info("Service not found, creating", "name", service.Name)
-		return r.Client.Create(context.TODO(), service)
-	}
-
-	// If changed, update
-	r.Log.Info("Service already present", "name", service.Name)
-	// service.ResourceVersion = found.GetResourceVersion()
-	// service.Spec = corev1.ServiceSpec{}
-	// This causes errors; not sure why
-	// return r.Client.Update(context.TODO(), service)
-	return nil
+	return r.createOrUpdateService(iter8, service)
 }
 
 func (r *Iter8Reconciler) serviceForAnalytics(iter8 *iter8v1alpha1.Iter8) *corev1.Service {
@@ -199,6 +212,10 @@ func (r *Iter8Reconciler) serviceForAnalytics(iter8 *iter8v1alpha1.Iter8) *corev
 		},
 	}
 
+	setServiceExposure(svc, iter8.Spec.AnalyticsEngine.Service, "http")
+	setMetadata(iter8, svc, labelsForComponent(iter8, analyticsDefaultName))
+	r.override(iter8, svc)
+
 	// Set Iter8 instance as the owner and controller
 	controllerutil.SetControllerReference(iter8, svc, r.Scheme)
 	return svc
@@ -208,19 +225,7 @@ func (r *Iter8Reconciler) createOrUpdateDeploymentForAnalytics(iter8 *iter8v1alp
 	// Desired state
 	deployment := r.deploymentForIter8Analytics(iter8)
 
-	// Get current state
-	found := &appsv1.Deployment{}
-	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: deployment.Name, Namespace: iter8.Namespace}, found)
-	if err != nil {
-		r.Log.Info("Deployment not found, creating", "name", deployment.Name)
-		return r.Client.Create(context.TODO(), deployment)
-	}
-
-	// If changed, update
-	r.Log.Info("Deployment already present", "name", deployment.Name)
-	// deployment.ResourceVersion = found.GetResourceVersion()
-	// return r.Client.Update(context.TODO(), deployment)
-	return nil
+	return r.createOrUpdateDeployment(iter8, deployment)
 }
 
 func (r *Iter8Reconciler) deploymentForIter8Analytics(iter8 *iter8v1alpha1.Iter8) *appsv1.Deployment {
@@ -228,7 +233,7 @@ func (r *Iter8Reconciler) deploymentForIter8Analytics(iter8 *iter8v1alpha1.Iter8
 		"app": analyticsDefaultName,
 	}
 
-	replicaCount := iter8v1alpha1.GetReplicaCount(iter8.Spec.AnalyticsEngine.Deployment)
+	replicaCount := iter8v1alpha1.GetComponentReplicaCount(iter8.Spec, iter8.Spec.AnalyticsEngine.Deployment)
 	port := iter8v1alpha1.GetServicePort(iter8.Spec.AnalyticsEngine.Service, analyticsDefaultServicePort)
 
 	deploy := &appsv1.Deployment{
@@ -248,7 +253,7 @@ func (r *Iter8Reconciler) deploymentForIter8Analytics(iter8 *iter8v1alpha1.Iter8
 				},
 				Spec: corev1.PodSpec{
 					Containers: []corev1.Container{{
-						Image:           iter8.Spec.AnalyticsEngine.Deployment.Image,
+						Image:           imageForIter8(iter8, analyticsDefaultName, iter8.Spec.AnalyticsEngine.Deployment.Image),
 						ImagePullPolicy: iter8v1alpha1.GetImagePullPolicy(iter8.Spec.AnalyticsEngine.Deployment),
 						Name:            analyticsDefaultName,
 						Env: []corev1.EnvVar{{
@@ -256,6 +261,7 @@ func (r *Iter8Reconciler) deploymentForIter8Analytics(iter8 *iter8v1alpha1.Iter8
 							Value: "config.yaml",
 						}},
 						Ports: []corev1.ContainerPort{{
+							Name:          "http",
 							ContainerPort: port,
 						}},
 						VolumeMounts: []corev1.VolumeMount{{
@@ -279,11 +285,32 @@ func (r *Iter8Reconciler) deploymentForIter8Analytics(iter8 *iter8v1alpha1.Iter8
 		},
 	}
 
+	// the number of replicas is left to the HorizontalPodAutoscaler
+	if nil != iter8.Spec.AnalyticsEngine.Autoscaling {
+		deploy.Spec.Replicas = nil
+	}
+
 	rsrc := iter8.Spec.AnalyticsEngine.Deployment.Resources
 	if nil != rsrc {
 		deploy.Spec.Template.Spec.Containers[0].Resources = *rsrc
 	}
 
+	// the analytics engine is live once it accepts connections and ready once its API responds
+	httpPort := intstr.FromString("http")
+	setProbes(&deploy.Spec.Template.Spec.Containers[0], iter8.Spec.AnalyticsEngine.Deployment,
+		defaultProbe(httpPort, "", 0, 3),
+		defaultProbe(httpPort, analyticsReadinessPath, 0, 3),
+		defaultProbe(httpPort, "", 0, 30))
+	setScheduling(&deploy.Spec.Template.Spec, iter8.Spec.AnalyticsEngine.Deployment)
+	setAntiAffinity(&deploy.Spec.Template.Spec, analyticsDefaultName, analyticsReplicas(iter8))
+	deploy.Spec.Template.Spec.ImagePullSecrets = iter8.Spec.AnalyticsEngine.Deployment.ImagePullSecrets
+	setContainers(&deploy.Spec.Template.Spec, iter8.Spec.AnalyticsEngine.Deployment)
+	setSecurityContext(&deploy.Spec.Template, iter8.Spec.AnalyticsEngine.Deployment, r.openShift)
+
+	setMetadata(iter8, deploy, labelsForComponent(iter8, analyticsDefaultName))
+	setPodMetadata(iter8, &deploy.Spec.Template, analyticsDefaultName)
+	r.override(iter8, deploy)
+
 	// Set Iter8 instance as the owner and controller
 	controllerutil.SetControllerReference(iter8, deploy, r.Scheme)
 	return deploy
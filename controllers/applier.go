@@ -0,0 +1,306 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+
+	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// Operations reported when applying an object
+const (
+	applyCreated   = "created"
+	applyUpdated   = "updated"
+	applyUnchanged = "unchanged"
+	// applySkipped is reported for an existing object not managed by the Iter8 resource
+	applySkipped = "skipped"
+)
+
+// applyResult records the outcome of applying one object
+type applyResult struct {
+	Kind      string
+	Name      string
+	Namespace string
+	Operation string
+	Err       error
+}
+
+// readManifests reads the objects in a YAML file of one or more documents. Kinds known to the scheme
+// are decoded to their types; other kinds are returned as unstructured objects.
+func readManifests(fileName string, scheme *runtime.Scheme) ([]runtime.Object, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := decodeManifests(data, scheme)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", fileName, err)
+	}
+	return objects, nil
+}
+
+// decodeManifests decodes the objects in YAML or JSON documents. Empty documents are ignored.
+func decodeManifests(data []byte, scheme *runtime.Scheme) ([]runtime.Object, error) {
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	objects := []runtime.Object{}
+	for {
+		u := &unstructured.Unstructured{}
+		err := decoder.Decode(&u.Object)
+		if err == io.EOF {
+			return objects, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(u.Object) == 0 {
+			continue
+		}
+
+		gvk := u.GroupVersionKind()
+		if gvk.Kind == "" {
+			return nil, fmt.Errorf("object %q has no kind", u.GetName())
+		}
+		if !scheme.Recognizes(gvk) {
+			objects = append(objects, u)
+			continue
+		}
+		obj, err := scheme.New(gvk)
+		if err != nil {
+			return nil, err
+		}
+		err = runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, obj)
+		if err != nil {
+			return nil, fmt.Errorf("%s %q: %s", gvk.Kind, u.GetName(), err)
+		}
+		obj.GetObjectKind().SetGroupVersionKind(gvk)
+		objects = append(objects, obj)
+	}
+}
+
+// applyManifests applies each object, continuing past failures. Failures are summarized in the error.
+func (r *Iter8Reconciler) applyManifests(iter8 *iter8v1alpha1.Iter8, objects []runtime.Object) ([]applyResult, error) {
+	results := make([]applyResult, 0, len(objects))
+	for _, obj := range objects {
+		results = append(results, r.apply(iter8, obj))
+	}
+	return results, applyResultsError(results)
+}
+
+// apply creates an object or updates it if it differs from the existing object. Objects in the namespace of
+// the Iter8 resource are owned by it; other objects are labeled with their owner unless labeled as shared
+// (see setSharedLabels). Existing objects not managed by the Iter8 resource are left unchanged.
+func (r *Iter8Reconciler) apply(iter8 *iter8v1alpha1.Iter8, obj runtime.Object) applyResult {
+	result := applyResult{Kind: obj.GetObjectKind().GroupVersionKind().Kind}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Name, result.Namespace = accessor.GetName(), accessor.GetNamespace()
+	if result.Kind == "" {
+		if kinds, _, err := r.Scheme.ObjectKinds(obj); err == nil && len(kinds) > 0 {
+			result.Kind = kinds[0].Kind
+		}
+	}
+
+	if accessor.GetNamespace() == iter8.Namespace {
+		err = controllerutil.SetControllerReference(iter8, accessor, r.Scheme)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+	} else if !isShared(accessor) {
+		setOwnerLabels(iter8, accessor)
+	}
+
+	found := obj.DeepCopyObject()
+	err = r.Client.Get(context.TODO(), types.NamespacedName{Name: result.Name, Namespace: result.Namespace}, found)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			r.Log.Info("Creating object", "kind", result.Kind, "name", result.Name, "namespace", result.Namespace)
+			result.Operation = applyCreated
+			result.Err = r.create(obj)
+			return result
+		}
+		result.Err = err
+		return result
+	}
+
+	foundAccessor, err := meta.Accessor(found)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	managed := r.isManaged(iter8, foundAccessor)
+	if isShared(accessor) {
+		managed = isManagedShared(foundAccessor)
+	}
+	if !managed {
+		r.Log.Info("Object already present and not managed by Iter8 resource", "kind", result.Kind, "name", result.Name, "namespace", result.Namespace)
+		result.Operation = applySkipped
+		return result
+	}
+
+	unchanged, err := isAppliedTo(obj, found)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if unchanged {
+		result.Operation = applyUnchanged
+		return result
+	}
+
+	r.Log.Info("Updating object", "kind", result.Kind, "name", result.Name, "namespace", result.Namespace)
+	accessor.SetResourceVersion(foundAccessor.GetResourceVersion())
+	result.Operation = applyUpdated
+	result.Err = r.Client.Update(context.TODO(), obj)
+	return result
+}
+
+// create creates an object, working around API server restrictions on some kinds
+func (r *Iter8Reconciler) create(obj runtime.Object) error {
+	if crd, ok := obj.(*apiextensionsv1beta1.CustomResourceDefinition); ok {
+		crdMutex.Lock()
+		defer crdMutex.Unlock()
+		return createCRD(r.Client, crd)
+	}
+	return r.Client.Create(context.TODO(), obj)
+}
+
+// isAppliedTo determines whether every field set in desired, other than its status and the metadata
+// managed by the API server, has the same value in found. Fields defaulted by the API server are ignored.
+func isAppliedTo(desired runtime.Object, found runtime.Object) (bool, error) {
+	d, err := toUnstructured(desired)
+	if err != nil {
+		return false, err
+	}
+	f, err := toUnstructured(found)
+	if err != nil {
+		return false, err
+	}
+
+	delete(d, "status")
+	delete(d, "apiVersion")
+	delete(d, "kind")
+	if m, ok := d["metadata"].(map[string]interface{}); ok {
+		d["metadata"] = map[string]interface{}{
+			"labels":          m["labels"],
+			"annotations":     m["annotations"],
+			"ownerReferences": m["ownerReferences"],
+		}
+	}
+	return isSubset(d, f), nil
+}
+
+// toUnstructured returns a copy of the content of an object
+func toUnstructured(obj runtime.Object) (map[string]interface{}, error) {
+	if u, ok := obj.(runtime.Unstructured); ok {
+		return runtime.DeepCopyJSON(u.UnstructuredContent()), nil
+	}
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+}
+
+// isSubset determines whether every value set in desired has the same value in found. Maps may have
+// additional keys in found; lists must have the same length.
+func isSubset(desired interface{}, found interface{}) bool {
+	switch d := desired.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		f, ok := found.(map[string]interface{})
+		if !ok {
+			return len(d) == 0
+		}
+		for key, value := range d {
+			if !isSubset(value, f[key]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		f, ok := found.([]interface{})
+		if !ok {
+			return len(d) == 0
+		}
+		if len(d) != len(f) {
+			return false
+		}
+		for i := range d {
+			if !isSubset(d[i], f[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		// numbers decoded from JSON are float64, while those read from the API server may be int64
+		if d, ok := toFloat64(desired); ok {
+			f, ok := toFloat64(found)
+			return ok && d == f
+		}
+		return reflect.DeepEqual(desired, found)
+	}
+}
+
+// toFloat64 converts a number to float64
+func toFloat64(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+// recordApplyResults reports the objects created, updated, skipped or failed as events on the Iter8 resource
+func (r *Iter8Reconciler) recordApplyResults(iter8 *iter8v1alpha1.Iter8, results []applyResult) {
+	for _, result := range results {
+		object := result.Kind + " " + result.Name
+		if result.Namespace != "" {
+			object = result.Kind + " " + result.Namespace + "/" + result.Name
+		}
+		switch {
+		case nil != result.Err:
+			r.Recorder.Event(iter8, corev1.EventTypeWarning, "ApplyFailed", fmt.Sprintf("Failed to apply %s: %s", object, result.Err))
+		case result.Operation == applySkipped:
+			r.Recorder.Event(iter8, corev1.EventTypeWarning, "NotManaged", object+" already present and not managed by Iter8 resource")
+		case result.Operation == applyCreated || result.Operation == applyUpdated:
+			r.Recorder.Event(iter8, corev1.EventTypeNormal, "Applied", object+" "+result.Operation)
+		}
+	}
+}
+
+// applyResultsError summarizes the failures in results, if any
+func applyResultsError(results []applyResult) error {
+	failed := []string{}
+	for _, result := range results {
+		if nil != result.Err {
+			failed = append(failed, fmt.Sprintf("%s %s: %s", result.Kind, result.Name, result.Err))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("failed to apply %d object(s): %v", len(failed), failed)
+}
@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+)
+
+func TestIsSubset(t *testing.T) {
+	tests := []struct {
+		name    string
+		desired interface{}
+		found   interface{}
+		want    bool
+	}{
+		{name: "nil desired", desired: nil, found: "anything", want: true},
+		{name: "equal values", desired: "a", found: "a", want: true},
+		{name: "different values", desired: "a", found: "b", want: false},
+		{name: "different types", desired: int64(1), found: "1", want: false},
+		{name: "JSON number found as integer", desired: float64(1), found: int64(1), want: true},
+		{name: "JSON number differs from integer", desired: float64(1.5), found: int64(1), want: false},
+		{
+			name:    "map with additional keys found",
+			desired: map[string]interface{}{"a": "1"},
+			found:   map[string]interface{}{"a": "1", "b": "2"},
+			want:    true,
+		}, {
+			name:    "map with key missing",
+			desired: map[string]interface{}{"a": "1", "b": "2"},
+			found:   map[string]interface{}{"a": "1"},
+			want:    false,
+		}, {
+			name:    "empty map not found",
+			desired: map[string]interface{}{},
+			found:   nil,
+			want:    true,
+		}, {
+			name:    "nested map differs",
+			desired: map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(2)}},
+			found:   map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(1), "paused": false}},
+			want:    false,
+		}, {
+			name:    "lists with defaulted fields",
+			desired: []interface{}{map[string]interface{}{"name": "a"}},
+			found:   []interface{}{map[string]interface{}{"name": "a", "protocol": "TCP"}},
+			want:    true,
+		}, {
+			name:    "lists of different lengths",
+			desired: []interface{}{"a"},
+			found:   []interface{}{"a", "b"},
+			want:    false,
+		}, {
+			name:    "empty list not found",
+			desired: []interface{}{},
+			found:   nil,
+			want:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isSubset(tt.desired, tt.found); got != tt.want {
+				t.Errorf("isSubset() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsAppliedTo(t *testing.T) {
+	desired := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "ns", Labels: map[string]string{"app": "a"}},
+		Data:       map[string]string{"key": "value"},
+	}
+
+	tests := []struct {
+		name  string
+		found func(*corev1.ConfigMap)
+		want  bool
+	}{{
+		name:  "unchanged",
+		found: func(cm *corev1.ConfigMap) {},
+		want:  true,
+	}, {
+		name: "metadata managed by the API server",
+		found: func(cm *corev1.ConfigMap) {
+			cm.ResourceVersion = "1"
+			cm.UID = "uid"
+			cm.Labels["other"] = "label"
+		},
+		want: true,
+	}, {
+		name:  "data changed",
+		found: func(cm *corev1.ConfigMap) { cm.Data["key"] = "other" },
+		want:  false,
+	}, {
+		name:  "label removed",
+		found: func(cm *corev1.ConfigMap) { delete(cm.Labels, "app") },
+		want:  false,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found := desired.DeepCopy()
+			tt.found(found)
+			got, err := isAppliedTo(desired, found)
+			if err != nil {
+				t.Fatalf("isAppliedTo() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("isAppliedTo() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeManifests(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+
+	tests := []struct {
+		name      string
+		data      string
+		wantTypes []string
+		wantErr   bool
+	}{{
+		name: "known and unknown kinds",
+		data: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cm
+---
+apiVersion: route.openshift.io/v1
+kind: Route
+metadata:
+  name: route
+`,
+		wantTypes: []string{"*v1.ConfigMap", "*unstructured.Unstructured"},
+	}, {
+		name:      "empty documents ignored",
+		data:      "---\n---\napiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: sa\n---\n",
+		wantTypes: []string{"*v1.ServiceAccount"},
+	}, {
+		name:      "JSON",
+		data:      `{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "svc"}}`,
+		wantTypes: []string{"*v1.Service"},
+	}, {
+		name:      "no documents",
+		data:      "",
+		wantTypes: []string{},
+	}, {
+		name:    "missing kind",
+		data:    "apiVersion: v1\nmetadata:\n  name: cm\n",
+		wantErr: true,
+	}, {
+		name:    "field of the wrong type",
+		data:    "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm\ndata: [a]\n",
+		wantErr: true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects, err := decodeManifests([]byte(tt.data), scheme)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeManifests() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(objects) != len(tt.wantTypes) {
+				t.Fatalf("decodeManifests() returned %d objects, want %d", len(objects), len(tt.wantTypes))
+			}
+			for i, obj := range objects {
+				if got := fmt.Sprintf("%T", obj); got != tt.wantTypes[i] {
+					t.Errorf("object %d is %s, want %s", i, got, tt.wantTypes[i])
+				}
+				if obj.GetObjectKind().GroupVersionKind().Kind == "" {
+					t.Errorf("object %d has no kind", i)
+				}
+			}
+		})
+	}
+}
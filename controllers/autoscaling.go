@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"context"
+
+	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// createOrUpdateAutoscalerForAnalytics creates or updates the HorizontalPodAutoscaler of the analytics engine,
+// or deletes it if autoscaling is not specified
+func (r *Iter8Reconciler) createOrUpdateAutoscalerForAnalytics(iter8 *iter8v1alpha1.Iter8) error {
+	found := &autoscalingv2beta2.HorizontalPodAutoscaler{}
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: analyticsDefaultName, Namespace: iter8.Namespace}, found)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	exists := err == nil
+
+	if nil == iter8.Spec.AnalyticsEngine.Autoscaling {
+		if exists && metav1.IsControlledBy(found, iter8) {
+			r.Log.Info("Autoscaling disabled, deleting HorizontalPodAutoscaler", "name", found.Name)
+			err = r.Client.Delete(context.TODO(), found)
+			if err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// Desired state
+	hpa := r.autoscalerForAnalytics(iter8)
+	if !exists {
+		r.Log.Info("HorizontalPodAutoscaler not found, creating", "name", hpa.Name)
+		return r.Client.Create(context.TODO(), hpa)
+	}
+
+	// If changed, update
+	if hasAutoscalerSpec(found, hpa) && hasMetadata(found, hpa) {
+		r.Log.Info("HorizontalPodAutoscaler already present", "name", hpa.Name)
+		return nil
+	}
+	r.Log.Info("HorizontalPodAutoscaler changed, updating", "name", hpa.Name)
+	found.Spec = hpa.Spec
+	mergeMetadata(found, hpa)
+	return r.Client.Update(context.TODO(), found)
+}
+
+// hasAutoscalerSpec determines whether a HorizontalPodAutoscaler has the spec of the desired one. Only the
+// fields set by the operator are compared since the API server defaults others, such as the scaling behavior.
+func hasAutoscalerSpec(found *autoscalingv2beta2.HorizontalPodAutoscaler, desired *autoscalingv2beta2.HorizontalPodAutoscaler) bool {
+	return found.Spec.ScaleTargetRef == desired.Spec.ScaleTargetRef &&
+		equality.Semantic.DeepEqual(found.Spec.MinReplicas, desired.Spec.MinReplicas) &&
+		found.Spec.MaxReplicas == desired.Spec.MaxReplicas &&
+		equality.Semantic.DeepEqual(found.Spec.Metrics, desired.Spec.Metrics)
+}
+
+func (r *Iter8Reconciler) autoscalerForAnalytics(iter8 *iter8v1alpha1.Iter8) *autoscalingv2beta2.HorizontalPodAutoscaler {
+	autoscaling := *iter8.Spec.AnalyticsEngine.Autoscaling
+	minReplicas := int32(1)
+	if nil != autoscaling.MinReplicas {
+		minReplicas = *autoscaling.MinReplicas
+	}
+
+	metrics := []autoscalingv2beta2.MetricSpec{}
+	if target := iter8v1alpha1.GetTargetCPUUtilizationPercentage(autoscaling); nil != target {
+		metrics = append(metrics, autoscalingv2beta2.MetricSpec{
+			Type: autoscalingv2beta2.ResourceMetricSourceType,
+			Resource: &autoscalingv2beta2.ResourceMetricSource{
+				Name: corev1.ResourceCPU,
+				Target: autoscalingv2beta2.MetricTarget{
+					Type:               autoscalingv2beta2.UtilizationMetricType,
+					AverageUtilization: target,
+				},
+			},
+		})
+	}
+	metrics = append(metrics, autoscaling.Metrics...)
+
+	hpa := &autoscalingv2beta2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      analyticsDefaultName,
+			Namespace: iter8.Namespace,
+			Labels: map[string]string{
+				"app": analyticsDefaultName,
+			},
+		},
+		Spec: autoscalingv2beta2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2beta2.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       analyticsDefaultName,
+			},
+			MinReplicas: &minReplicas,
+			MaxReplicas: autoscaling.MaxReplicas,
+			Metrics:     metrics,
+		},
+	}
+
+	setMetadata(iter8, hpa, labelsForComponent(iter8, analyticsDefaultName))
+	r.override(iter8, hpa)
+
+	// Set Iter8 instance as the owner and controller
+	controllerutil.SetControllerReference(iter8, hpa, r.Scheme)
+	return hpa
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"testing"
+
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestHasAutoscalerSpec(t *testing.T) {
+	minReplicas := int32(1)
+	utilization := int32(80)
+	desired := &autoscalingv2beta2.HorizontalPodAutoscaler{
+		Spec: autoscalingv2beta2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2beta2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "iter8-analytics"},
+			MinReplicas:    &minReplicas,
+			MaxReplicas:    3,
+			Metrics: []autoscalingv2beta2.MetricSpec{{
+				Type: autoscalingv2beta2.ResourceMetricSourceType,
+				Resource: &autoscalingv2beta2.ResourceMetricSource{
+					Name:   corev1.ResourceCPU,
+					Target: autoscalingv2beta2.MetricTarget{Type: autoscalingv2beta2.UtilizationMetricType, AverageUtilization: &utilization},
+				},
+			}},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		found func(*autoscalingv2beta2.HorizontalPodAutoscaler)
+		want  bool
+	}{{
+		name:  "unchanged",
+		found: func(hpa *autoscalingv2beta2.HorizontalPodAutoscaler) {},
+		want:  true,
+	}, {
+		name:  "status ignored",
+		found: func(hpa *autoscalingv2beta2.HorizontalPodAutoscaler) { hpa.Status.CurrentReplicas = 2 },
+		want:  true,
+	}, {
+		name:  "scale target changed",
+		found: func(hpa *autoscalingv2beta2.HorizontalPodAutoscaler) { hpa.Spec.ScaleTargetRef.Name = "other" },
+		want:  false,
+	}, {
+		name:  "min replicas changed",
+		found: func(hpa *autoscalingv2beta2.HorizontalPodAutoscaler) { *hpa.Spec.MinReplicas = 2 },
+		want:  false,
+	}, {
+		name:  "max replicas changed",
+		found: func(hpa *autoscalingv2beta2.HorizontalPodAutoscaler) { hpa.Spec.MaxReplicas = 5 },
+		want:  false,
+	}, {
+		name: "target utilization changed",
+		found: func(hpa *autoscalingv2beta2.HorizontalPodAutoscaler) {
+			changed := int32(50)
+			hpa.Spec.Metrics[0].Resource.Target.AverageUtilization = &changed
+		},
+		want: false,
+	}, {
+		name:  "metrics removed",
+		found: func(hpa *autoscalingv2beta2.HorizontalPodAutoscaler) { hpa.Spec.Metrics = nil },
+		want:  false,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found := desired.DeepCopy()
+			tt.found(found)
+			if got := hasAutoscalerSpec(found, desired); got != tt.want {
+				t.Errorf("hasAutoscalerSpec() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"context"
+
+	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	hostnameTopologyKey = "kubernetes.io/hostname"
+	zoneTopologyKey     = "topology.kubernetes.io/zone"
+
+	leaderElectionFlag = "--enable-leader-election"
+)
+
+// controllerReplicas returns the number of replicas of the controller
+func controllerReplicas(iter8 *iter8v1alpha1.Iter8) int32 {
+	return iter8v1alpha1.GetComponentReplicaCount(iter8.Spec, iter8.Spec.Controller.Deployment)
+}
+
+// analyticsReplicas returns the minimum number of replicas of the analytics engine
+func analyticsReplicas(iter8 *iter8v1alpha1.Iter8) int32 {
+	autoscaling := iter8.Spec.AnalyticsEngine.Autoscaling
+	if nil == autoscaling {
+		return iter8v1alpha1.GetComponentReplicaCount(iter8.Spec, iter8.Spec.AnalyticsEngine.Deployment)
+	}
+	if nil == autoscaling.MinReplicas {
+		return 1
+	}
+	return *autoscaling.MinReplicas
+}
+
+// setAntiAffinity spreads the replicas of a component across nodes and zones, unless there is only
+// one replica or an affinity is specified
+func setAntiAffinity(pod *corev1.PodSpec, app string, replicas int32) {
+	if replicas <= 1 || nil != pod.Affinity {
+		return
+	}
+	selector := &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			"app": app,
+		},
+	}
+	pod.Affinity = &corev1.Affinity{
+		PodAntiAffinity: &corev1.PodAntiAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{{
+				Weight: 100,
+				PodAffinityTerm: corev1.PodAffinityTerm{
+					LabelSelector: selector,
+					TopologyKey:   hostnameTopologyKey,
+				},
+			}, {
+				Weight: 50,
+				PodAffinityTerm: corev1.PodAffinityTerm{
+					LabelSelector: selector,
+					TopologyKey:   zoneTopologyKey,
+				},
+			}},
+		},
+	}
+}
+
+// createOrUpdatePodDisruptionBudget creates or updates the PodDisruptionBudget of a component with more than
+// one replica, or deletes it if there is only one
+func (r *Iter8Reconciler) createOrUpdatePodDisruptionBudget(iter8 *iter8v1alpha1.Iter8, app string, replicas int32) error {
+	found := &policyv1beta1.PodDisruptionBudget{}
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: app, Namespace: iter8.Namespace}, found)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	exists := err == nil
+
+	if replicas <= 1 {
+		if exists && metav1.IsControlledBy(found, iter8) {
+			r.Log.Info("Single replica, deleting PodDisruptionBudget", "name", found.Name)
+			err = r.Client.Delete(context.TODO(), found)
+			if err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// Desired state
+	pdb := r.podDisruptionBudgetForIter8(iter8, app)
+	if !exists {
+		r.Log.Info("PodDisruptionBudget not found, creating", "name", pdb.Name)
+		return r.Client.Create(context.TODO(), pdb)
+	}
+
+	// If changed, update
+	if equality.Semantic.DeepEqual(found.Spec, pdb.Spec) && hasMetadata(found, pdb) {
+		r.Log.Info("PodDisruptionBudget already present", "name", pdb.Name)
+		return nil
+	}
+	r.Log.Info("PodDisruptionBudget changed, updating", "name", pdb.Name)
+	found.Spec = pdb.Spec
+	mergeMetadata(found, pdb)
+	return r.Client.Update(context.TODO(), found)
+}
+
+func (r *Iter8Reconciler) podDisruptionBudgetForIter8(iter8 *iter8v1alpha1.Iter8, app string) *policyv1beta1.PodDisruptionBudget {
+	labels := map[string]string{
+		"app": app,
+	}
+	maxUnavailable := intstr.FromInt(1)
+
+	pdb := &policyv1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      app,
+			Namespace: iter8.Namespace,
+			Labels:    labels,
+		},
+		Spec: policyv1beta1.PodDisruptionBudgetSpec{
+			MaxUnavailable: &maxUnavailable,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+		},
+	}
+
+	setMetadata(iter8, pdb, labelsForComponent(iter8, app))
+	r.override(iter8, pdb)
+
+	// Set Iter8 instance as the owner and controller
+	controllerutil.SetControllerReference(iter8, pdb, r.Scheme)
+	return pdb
+}
@@ -1,100 +1,55 @@
 package controllers
 
 import (
-	"bytes"
 	"context"
 	"fmt"
-	"os"
 	"strings"
 	"sync"
 
 	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
 	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
-	"k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/api/meta"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/yaml"
 )
 
-func (r *Iter8Reconciler) crdsForIter8(iter8 *iter8v1alpha1.Iter8) error {
-	found := &apiextensionsv1beta1.CustomResourceDefinition{}
-	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: "experiments.iter8.tools"}, found)
-	if err != nil {
-		// could not Get
-		if errors.IsNotFound(err) {
-			err = InstallCRD(r.Client)
-			if err != nil {
-				ctrl.Log.Error(err, "Failed to create CustomResourceDefinition")
-				return err
-			}
-		} else {
-			ctrl.Log.Error(err, "Failed to create CustomResourceDefinition: search failed")
-			return err
-		}
-	} else {
-		// // make sure has owner
-		// owner := found.GetOwnerReferences()
-		// if 0 == len(owner) {
-		// 	ctrl.Log.Info("Updating owner on CustomResourceDefinition", "name", found.Name)
-		// 	// Set Iter8 instance as the owner and controller
-		// 	controllerutil.SetControllerReference(iter8, found, r.scheme)
-		// 	err = r.client.Update(context.TODO(), found)
-		// 	return err
-		// }
-		ctrl.Log.Info("CustomResourceDefinition already present", "name", found.Name)
-	}
-	return nil
-}
+// crdManifest defines the experiments CustomResourceDefinition
+const crdManifest = "config/iter8/iter8.tools_experiments.yaml"
 
-var crdMutex sync.Mutex // ensure two workers don't deploy CRDs at same time
-
-// InstallCRD makes sure the CRD has been installed
-// CRD is installed from config/iter8/iter8.tools_experiments.yaml
-func InstallCRD(cl client.Client) error {
-	crdMutex.Lock()
-	defer crdMutex.Unlock()
-
-	file, err := os.Open("config/iter8/iter8.tools_experiments.yaml")
+func (r *Iter8Reconciler) crdsForIter8(iter8 *iter8v1alpha1.Iter8) error {
+	objects, err := readManifests(crdManifest, r.Scheme)
 	if err != nil {
+		ctrl.Log.Error(err, "Failed to read CustomResourceDefinition")
 		return err
 	}
-	defer file.Close()
-
-	buf := &bytes.Buffer{}
-	if _, err = buf.ReadFrom(file); err != nil {
-		return err
+	// the CustomResourceDefinition is shared by all Iter8 instances
+	for _, obj := range objects {
+		if accessor, err := meta.Accessor(obj); err == nil {
+			setSharedLabels(accessor)
+		}
 	}
-
-	crd, err := decodeCRD(string(buf.Bytes()))
+	results, err := r.applyManifests(iter8, objects)
+	r.recordApplyResults(iter8, results)
 	if err != nil {
-		return err
+		ctrl.Log.Error(err, "Failed to create CustomResourceDefinition")
 	}
-
-	if err = createCRD(cl, crd); err != nil {
-		return err
-	}
-
-	return nil
+	return err
 }
 
-func decodeCRD(raw string) (*apiextensionsv1beta1.CustomResourceDefinition, error) {
-	rawJSON, err := yaml.YAMLToJSON([]byte(raw))
+var crdMutex sync.Mutex // ensure two workers don't deploy CRDs at same time
+
+// readCRD reads the CRD from config/iter8/iter8.tools_experiments.yaml
+func (r *Iter8Reconciler) readCRD() (*apiextensionsv1beta1.CustomResourceDefinition, error) {
+	objects, err := readManifests(crdManifest, r.Scheme)
 	if err != nil {
-		ctrl.Log.Error(err, "unable to convert raw data to JSON")
-		return nil, err
-	}
-	obj := &apiextensionsv1beta1.CustomResourceDefinition{}
-	if _, _, err = unstructured.UnstructuredJSONScheme.Decode(rawJSON, nil, obj); err != nil {
-		ctrl.Log.Error(err, "unable to decode object into Unstructured")
 		return nil, err
 	}
-	if obj.GroupVersionKind().GroupKind().String() == "CustomResourceDefinition.apiextensions.k8s.io" {
-		return obj, nil
+	for _, obj := range objects {
+		if crd, ok := obj.(*apiextensionsv1beta1.CustomResourceDefinition); ok {
+			return crd, nil
+		}
 	}
-	return nil, nil
-
+	return nil, fmt.Errorf("No CustomResourceDefinition found in %s", crdManifest)
 }
 
 func createCRD(cl client.Client, crd *apiextensionsv1beta1.CustomResourceDefinition) error {
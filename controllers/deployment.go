@@ -0,0 +1,205 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// specHashAnnotation records the hash of the desired spec of a Deployment so that changes can be detected
+const specHashAnnotation = "iter8.tools/spec-hash"
+
+// createOrUpdateDeployment creates a Deployment or replaces its spec when the desired spec has changed or
+// the live spec no longer has the fields set by the operator. If the desired number of replicas is not set,
+// the current number is kept. An existing Deployment not managed by the Iter8 resource is left unchanged
+// until its adoption is confirmed (see adoptionForIter8).
+func (r *Iter8Reconciler) createOrUpdateDeployment(iter8 *iter8v1alpha1.Iter8, deployment *appsv1.Deployment) error {
+	hash, err := specHash(deployment.Spec)
+	if err != nil {
+		return err
+	}
+	if nil == deployment.Annotations {
+		deployment.Annotations = map[string]string{}
+	}
+	deployment.Annotations[specHashAnnotation] = hash
+
+	// Get current state
+	found := &appsv1.Deployment{}
+	err = r.Client.Get(context.TODO(), types.NamespacedName{Name: deployment.Name, Namespace: deployment.Namespace}, found)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			r.Log.Info("Deployment not found, creating", "name", deployment.Name)
+			return r.Client.Create(context.TODO(), deployment)
+		}
+		return err
+	}
+
+	if !r.isManaged(iter8, found) {
+		r.Log.Info("Deployment already present and not managed by Iter8 resource", "name", deployment.Name)
+		return nil
+	}
+
+	// If changed, update. The hash detects fields no longer desired; the comparison detects edits to the live spec.
+	applied, err := isAppliedTo(deployment, found)
+	if err != nil {
+		return err
+	}
+	if found.Annotations[specHashAnnotation] == hash && applied && hasMetadata(found, deployment) {
+		r.Log.Info("Deployment already present", "name", deployment.Name)
+		return nil
+	}
+	r.Log.Info("Deployment changed, updating", "name", deployment.Name)
+	deployment.ResourceVersion = found.GetResourceVersion()
+	if nil == deployment.Spec.Replicas {
+		// keep the number of replicas set by an autoscaler
+		deployment.Spec.Replicas = found.Spec.Replicas
+	}
+	return r.Client.Update(context.TODO(), deployment)
+}
+
+// specHash returns a hash of a Deployment spec
+func specHash(spec appsv1.DeploymentSpec) (string, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:16], nil
+}
+
+// setScheduling applies the scheduling controls in deploy to a pod spec
+func setScheduling(pod *corev1.PodSpec, deploy iter8v1alpha1.DeploymentSpec) {
+	pod.NodeSelector = deploy.NodeSelector
+	pod.Tolerations = deploy.Tolerations
+	pod.Affinity = deploy.Affinity
+	pod.TopologySpreadConstraints = deploy.TopologySpreadConstraints
+	if nil != deploy.PriorityClassName {
+		pod.PriorityClassName = *deploy.PriorityClassName
+	}
+}
+
+// setContainers merges the env, volumes and containers in deploy into a pod spec whose first container
+// is the component
+func setContainers(pod *corev1.PodSpec, deploy iter8v1alpha1.DeploymentSpec) {
+	container := &pod.Containers[0]
+	container.Env = mergeEnv(container.Env, deploy.Env)
+	container.EnvFrom = append(container.EnvFrom, deploy.EnvFrom...)
+	container.VolumeMounts = append(container.VolumeMounts, deploy.VolumeMounts...)
+
+	pod.Volumes = append(pod.Volumes, deploy.Volumes...)
+	pod.Containers = append(pod.Containers, deploy.ExtraContainers...)
+	pod.InitContainers = append(pod.InitContainers, deploy.InitContainers...)
+}
+
+// mergeEnv returns env with the variables in overrides replacing those of the same name
+func mergeEnv(env []corev1.EnvVar, overrides []corev1.EnvVar) []corev1.EnvVar {
+	result := make([]corev1.EnvVar, 0, len(env)+len(overrides))
+	for _, e := range env {
+		if _, ok := findEnv(overrides, e.Name); !ok {
+			result = append(result, e)
+		}
+	}
+	return append(result, overrides...)
+}
+
+func findEnv(env []corev1.EnvVar, name string) (corev1.EnvVar, bool) {
+	for _, e := range env {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return corev1.EnvVar{}, false
+}
+
+// defaultProbe returns a probe of a container port. With a path, the probe is an HTTP GET of the path;
+// otherwise it opens a TCP connection.
+func defaultProbe(port intstr.IntOrString, path string, initialDelaySeconds int32, failureThreshold int32) *corev1.Probe {
+	probe := &corev1.Probe{
+		InitialDelaySeconds: initialDelaySeconds,
+		PeriodSeconds:       10,
+		TimeoutSeconds:      5,
+		FailureThreshold:    failureThreshold,
+	}
+	if path != "" {
+		probe.Handler.HTTPGet = &corev1.HTTPGetAction{Path: path, Port: port}
+	} else {
+		probe.Handler.TCPSocket = &corev1.TCPSocketAction{Port: port}
+	}
+	return probe
+}
+
+// setProbes sets the probes of a container, replacing the defaults with those specified in deploy
+func setProbes(container *corev1.Container, deploy iter8v1alpha1.DeploymentSpec, liveness *corev1.Probe, readiness *corev1.Probe, startup *corev1.Probe) {
+	container.LivenessProbe = liveness
+	if nil != deploy.LivenessProbe {
+		container.LivenessProbe = deploy.LivenessProbe
+	}
+	container.ReadinessProbe = readiness
+	if nil != deploy.ReadinessProbe {
+		container.ReadinessProbe = deploy.ReadinessProbe
+	}
+	container.StartupProbe = startup
+	if nil != deploy.StartupProbe {
+		container.StartupProbe = deploy.StartupProbe
+	}
+}
+
+// componentsForIter8 records in status whether the Deployment of each component has all replicas ready
+func (r *Iter8Reconciler) componentsForIter8(iter8 *iter8v1alpha1.Iter8) error {
+	original := iter8.Status.DeepCopy()
+	components := []iter8v1alpha1.ComponentStatus{}
+	notReady := []string{}
+	for _, name := range []string{controllerDefaultName, analyticsDefaultName} {
+		status := iter8v1alpha1.ComponentStatus{Name: name}
+		found := &appsv1.Deployment{}
+		err := r.Client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: iter8.Namespace}, found)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		if err == nil {
+			status.Replicas = 1
+			if nil != found.Spec.Replicas {
+				status.Replicas = *found.Spec.Replicas
+			}
+			status.ReadyReplicas = found.Status.ReadyReplicas
+			status.Ready = found.Status.ObservedGeneration >= found.Generation &&
+				found.Status.UpdatedReplicas >= status.Replicas && status.ReadyReplicas >= status.Replicas
+		}
+		if !status.Ready {
+			notReady = append(notReady, name)
+		}
+		components = append(components, status)
+	}
+	iter8.Status.Components = components
+
+	condition := iter8v1alpha1.Condition{
+		Type:   iter8v1alpha1.Iter8ConditionReady,
+		Status: corev1.ConditionTrue,
+		Reason: iter8v1alpha1.Iter8ReasonComponentsReady,
+	}
+	if len(notReady) > 0 {
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = iter8v1alpha1.Iter8ReasonComponentsNotReady
+		condition.Message = "Not ready: " + strings.Join(notReady, ", ")
+	}
+	iter8v1alpha1.SetCondition(&iter8.Status.Conditions, condition)
+
+	if equality.Semantic.DeepEqual(original, &iter8.Status) {
+		return nil
+	}
+	err := r.Client.Status().Update(context.TODO(), iter8)
+	if err != nil {
+		r.Log.Error(err, "Unable to update Iter8 status")
+	}
+	return nil
+}
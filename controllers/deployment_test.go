@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestCreateOrUpdateDeployment(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = iter8v1alpha1.AddToScheme(scheme)
+	iter8 := iter8ForTest("iter8", "iter8", 0)
+
+	// desired returns the Deployment of the component, controlled by iter8
+	desired := func() *appsv1.Deployment {
+		deploy := deploymentForTest()
+		_ = controllerutil.SetControllerReference(&iter8, deploy, scheme)
+		return deploy
+	}
+
+	tests := []struct {
+		name      string
+		found     func() *appsv1.Deployment
+		wantImage string
+	}{{
+		name: "unmanaged Deployment left unchanged",
+		found: func() *appsv1.Deployment {
+			deploy := deploymentForTest()
+			deploy.Spec.Template.Spec.Containers[0].Image = "iter8/iter8-controller:helm"
+			return deploy
+		},
+		wantImage: "iter8/iter8-controller:helm",
+	}, {
+		name: "edited live spec restored",
+		found: func() *appsv1.Deployment {
+			deploy := desired()
+			deploy.Annotations = map[string]string{}
+			hash, _ := specHash(deploy.Spec)
+			deploy.Annotations[specHashAnnotation] = hash
+			deploy.Spec.Template.Spec.Containers[0].Image = "iter8/iter8-controller:edited"
+			return deploy
+		},
+		wantImage: "iter8/iter8-controller:v1",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Iter8Reconciler{
+				Client: fake.NewFakeClientWithScheme(scheme, &iter8, tt.found()),
+				Log:    logf.Log.WithName("test"),
+				Scheme: scheme,
+			}
+			if err := r.createOrUpdateDeployment(&iter8, desired()); err != nil {
+				t.Fatalf("createOrUpdateDeployment() error = %v", err)
+			}
+
+			found := &appsv1.Deployment{}
+			if err := r.Client.Get(context.TODO(), types.NamespacedName{Name: "iter8-controller", Namespace: "iter8"}, found); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if image := found.Spec.Template.Spec.Containers[0].Image; image != tt.wantImage {
+				t.Errorf("image = %q, want %q", image, tt.wantImage)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"context"
+
+	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	experimentEditorRoleName = "iter8-experiment-editor"
+	experimentViewerRoleName = "iter8-experiment-viewer"
+
+	aggregateToAdminLabel = "rbac.authorization.k8s.io/aggregate-to-admin"
+	aggregateToEditLabel  = "rbac.authorization.k8s.io/aggregate-to-edit"
+	aggregateToViewLabel  = "rbac.authorization.k8s.io/aggregate-to-view"
+)
+
+// experimentRolesForIter8 creates the user-facing ClusterRoles for experiments, or deletes them if disabled.
+// The ClusterRoles are shared by all Iter8 instances; they are deleted only when no Iter8 instance enables them.
+func (r *Iter8Reconciler) experimentRolesForIter8(iter8 *iter8v1alpha1.Iter8) error {
+	roles := []*rbacv1.ClusterRole{
+		experimentEditorRoleForIter8(),
+		experimentViewerRoleForIter8(),
+	}
+
+	enabled, err := r.experimentRolesEnabled(iter8)
+	if err != nil {
+		return err
+	}
+	for _, role := range roles {
+		if enabled {
+			err = r.createOrUpdateExperimentRole(role)
+		} else {
+			err = r.deleteIfShared(&rbacv1.ClusterRole{}, role.Name)
+		}
+		if err != nil {
+			ctrl.Log.Error(err, "Failed to reconcile ClusterRole", "name", role.Name)
+			return err
+		}
+	}
+	return nil
+}
+
+// experimentRolesEnabled determines whether any Iter8 instance enables the experiment roles
+func (r *Iter8Reconciler) experimentRolesEnabled(iter8 *iter8v1alpha1.Iter8) (bool, error) {
+	if iter8v1alpha1.GetExperimentRoles(iter8.Spec) {
+		return true, nil
+	}
+	others, err := r.otherIter8s(iter8)
+	if err != nil {
+		return false, err
+	}
+	for _, other := range others {
+		if iter8v1alpha1.GetExperimentRoles(other.Spec) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *Iter8Reconciler) createOrUpdateExperimentRole(role *rbacv1.ClusterRole) error {
+	found := &rbacv1.ClusterRole{}
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: role.Name}, found)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return r.Client.Create(context.TODO(), role)
+		}
+		return err
+	}
+
+	if !isManagedShared(found) {
+		ctrl.Log.Info("ClusterRole already present", "name", found.Name)
+		return nil
+	}
+
+	// If changed, update
+	if !equality.Semantic.DeepEqual(found.Rules, role.Rules) || !hasMetadata(found, role) {
+		ctrl.Log.Info("Updating ClusterRole", "name", role.Name)
+		found.Rules = role.Rules
+		mergeMetadata(found, role)
+		return r.Client.Update(context.TODO(), found)
+	}
+	return nil
+}
+
+func experimentEditorRoleForIter8() *rbacv1.ClusterRole {
+	role := &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: experimentEditorRoleName,
+			Labels: map[string]string{
+				aggregateToAdminLabel: "true",
+				aggregateToEditLabel:  "true",
+			},
+		},
+		Rules: []rbacv1.PolicyRule{{
+			APIGroups: []string{"iter8.tools"},
+			Resources: []string{"experiments"},
+			Verbs:     []string{"get", "list", "watch", "create", "update", "patch", "delete"},
+		}, {
+			APIGroups: []string{"iter8.tools"},
+			Resources: []string{"experiments/status"},
+			Verbs:     []string{"get"},
+		}},
+	}
+	setSharedLabels(role)
+	return role
+}
+
+func experimentViewerRoleForIter8() *rbacv1.ClusterRole {
+	role := &rbacv1.ClusterRole{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: experimentViewerRoleName,
+			Labels: map[string]string{
+				aggregateToViewLabel: "true",
+			},
+		},
+		Rules: []rbacv1.PolicyRule{{
+			APIGroups: []string{"iter8.tools"},
+			Resources: []string{"experiments", "experiments/status"},
+			Verbs:     []string{"get", "list", "watch"},
+		}},
+	}
+	setSharedLabels(role)
+	return role
+}
@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestCreateOrUpdateExperimentRoleKeepsLabels(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+
+	found := experimentViewerRoleForIter8()
+	found.Rules = nil
+	found.Labels["other"] = "label"
+	r := &Iter8Reconciler{
+		Client: fake.NewFakeClientWithScheme(scheme, found),
+		Log:    logf.Log.WithName("test"),
+		Scheme: scheme,
+	}
+
+	desired := experimentViewerRoleForIter8()
+	if err := r.createOrUpdateExperimentRole(desired); err != nil {
+		t.Fatalf("createOrUpdateExperimentRole() error = %v", err)
+	}
+
+	got := &rbacv1.ClusterRole{}
+	if err := r.Client.Get(context.TODO(), types.NamespacedName{Name: desired.Name}, got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Rules) != len(desired.Rules) {
+		t.Errorf("rules = %v, want %v", got.Rules, desired.Rules)
+	}
+	if got.Labels["other"] != "label" {
+		t.Errorf("labels = %v, want label other kept", got.Labels)
+	}
+	if !hasMetadata(got, desired) {
+		t.Errorf("labels = %v, want labels of %v", got.Labels, desired.Labels)
+	}
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// imageForIter8 returns the image used for a component: the specified image with its registry rewritten
+// and, if digests are pinned, referred to by the digest recorded in status
+func imageForIter8(iter8 *iter8v1alpha1.Iter8, component string, image string) string {
+	image = rewriteImage(image, iter8.Spec.ImageRegistry, iter8.Spec.ImageRegistryRewrites)
+	if !iter8v1alpha1.GetPinImageDigests(iter8.Spec) {
+		return image
+	}
+	for _, status := range iter8.Status.Images {
+		if status.Component == component && status.Image == image && status.Digest != "" {
+			return imageRepository(image) + "@" + status.Digest
+		}
+	}
+	return image
+}
+
+// rewriteImage applies the longest matching prefix rewrite to an image or, if none match, replaces its registry
+func rewriteImage(image string, registry *string, rewrites map[string]string) string {
+	prefixes := make([]string, 0, len(rewrites))
+	for prefix := range rewrites {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+
+	for _, prefix := range prefixes {
+		p := strings.TrimSuffix(prefix, "/")
+		for _, named := range []string{image, qualifiedImage(image)} {
+			if named == p || strings.HasPrefix(named, p+"/") || strings.HasPrefix(named, p+":") {
+				return strings.TrimSuffix(rewrites[prefix], "/") + named[len(p):]
+			}
+		}
+	}
+
+	if nil == registry || *registry == "" {
+		return image
+	}
+	_, path := splitRegistry(image)
+	return strings.TrimSuffix(*registry, "/") + "/" + path
+}
+
+// splitRegistry splits an image into its registry, if any, and the remainder. As for docker, the first
+// component is a registry only if it contains a "." or ":" or is localhost.
+func splitRegistry(image string) (string, string) {
+	i := strings.Index(image, "/")
+	if i < 0 {
+		return "", image
+	}
+	first := image[:i]
+	if strings.ContainsAny(first, ".:") || first == "localhost" {
+		return first, image[i+1:]
+	}
+	return "", image
+}
+
+// qualifiedImage returns an image with the implicit docker.io registry and library namespace made explicit
+func qualifiedImage(image string) string {
+	registry, path := splitRegistry(image)
+	if registry != "" {
+		return image
+	}
+	if !strings.Contains(path, "/") {
+		path = "library/" + path
+	}
+	return "docker.io/" + path
+}
+
+// imageRepository returns an image without its tag or digest
+func imageRepository(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image
+}
+
+// imagesForIter8 records in status the image of each component and the digest it resolved to,
+// as reported by the running pods
+func (r *Iter8Reconciler) imagesForIter8(iter8 *iter8v1alpha1.Iter8) error {
+	components := []struct {
+		name  string
+		image string
+	}{
+		{controllerDefaultName, rewriteImage(iter8.Spec.Controller.Deployment.Image, iter8.Spec.ImageRegistry, iter8.Spec.ImageRegistryRewrites)},
+		{analyticsDefaultName, rewriteImage(iter8.Spec.AnalyticsEngine.Deployment.Image, iter8.Spec.ImageRegistry, iter8.Spec.ImageRegistryRewrites)},
+	}
+
+	images := []iter8v1alpha1.ImageStatus{}
+	for _, component := range components {
+		status := iter8v1alpha1.ImageStatus{Component: component.name, Image: component.image}
+		digest, err := r.imageDigest(iter8, component.name, component.image)
+		if err != nil {
+			return err
+		}
+		status.Digest = digest
+		if status.Digest == "" {
+			// keep the digest recorded earlier until a pod reports one
+			for _, previous := range iter8.Status.Images {
+				if previous.Component == status.Component && previous.Image == status.Image {
+					status.Digest = previous.Digest
+				}
+			}
+		}
+		images = append(images, status)
+	}
+
+	if equality.Semantic.DeepEqual(images, iter8.Status.Images) {
+		return nil
+	}
+	r.Log.Info("Updating image status", "images", images)
+	iter8.Status.Images = images
+	err := r.Client.Status().Update(context.TODO(), iter8)
+	if err != nil {
+		r.Log.Error(err, "Unable to update Iter8 status")
+	}
+	return nil
+}
+
+// imageDigest returns the digest of the image of a component reported by a running pod, if any
+func (r *Iter8Reconciler) imageDigest(iter8 *iter8v1alpha1.Iter8, component string, image string) (string, error) {
+	pods := &corev1.PodList{}
+	err := r.Client.List(context.TODO(), pods, client.InNamespace(iter8.Namespace), client.MatchingLabels{"app": component})
+	if err != nil {
+		return "", err
+	}
+	repository := qualifiedImage(imageRepository(image))
+	for _, pod := range pods.Items {
+		for _, container := range pod.Status.ContainerStatuses {
+			if container.Name != component {
+				continue
+			}
+			// a pod runs the image if it refers to it by tag or, once pinned, by digest
+			pinned := strings.Contains(container.Image, "@") && qualifiedImage(imageRepository(container.Image)) == repository
+			if !pinned && qualifiedImage(container.Image) != qualifiedImage(image) {
+				continue
+			}
+			if i := strings.LastIndex(container.ImageID, "@"); i >= 0 {
+				return container.ImageID[i+1:], nil
+			}
+		}
+	}
+	return "", nil
+}
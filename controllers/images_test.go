@@ -0,0 +1,187 @@
+package controllers
+
+import (
+	"testing"
+
+	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
+)
+
+func TestRewriteImage(t *testing.T) {
+	tests := []struct {
+		name     string
+		image    string
+		registry string
+		rewrites map[string]string
+		want     string
+	}{{
+		name:  "unchanged",
+		image: "iter8/iter8-controller:v1.0.0",
+		want:  "iter8/iter8-controller:v1.0.0",
+	}, {
+		name:     "registry replaced",
+		image:    "iter8/iter8-controller:v1.0.0",
+		registry: "mirror.example.com/",
+		want:     "mirror.example.com/iter8/iter8-controller:v1.0.0",
+	}, {
+		name:     "explicit registry replaced",
+		image:    "quay.io/iter8/iter8-controller:v1.0.0",
+		registry: "mirror.example.com:5000",
+		want:     "mirror.example.com:5000/iter8/iter8-controller:v1.0.0",
+	}, {
+		name:     "prefix rewritten",
+		image:    "iter8/iter8-controller:v1.0.0",
+		rewrites: map[string]string{"iter8": "mirror.example.com/iter8-tools"},
+		want:     "mirror.example.com/iter8-tools/iter8-controller:v1.0.0",
+	}, {
+		name:     "qualified prefix rewritten",
+		image:    "iter8/iter8-controller:v1.0.0",
+		rewrites: map[string]string{"docker.io/iter8/": "mirror.example.com/iter8/"},
+		want:     "mirror.example.com/iter8/iter8-controller:v1.0.0",
+	}, {
+		name:     "longest prefix rewritten",
+		image:    "docker.io/iter8/iter8-analytics:v1.0.0",
+		rewrites: map[string]string{"docker.io": "a.example.com", "docker.io/iter8/iter8-analytics": "b.example.com/analytics"},
+		want:     "b.example.com/analytics:v1.0.0",
+	}, {
+		name:     "partial component not rewritten",
+		image:    "iter8/iter8-controller:v1.0.0",
+		rewrites: map[string]string{"iter": "mirror.example.com"},
+		want:     "iter8/iter8-controller:v1.0.0",
+	}, {
+		name:     "rewrite takes precedence over registry",
+		image:    "iter8/iter8-controller:v1.0.0",
+		registry: "registry.example.com",
+		rewrites: map[string]string{"iter8": "mirror.example.com/iter8"},
+		want:     "mirror.example.com/iter8/iter8-controller:v1.0.0",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var registry *string
+			if tt.registry != "" {
+				registry = &tt.registry
+			}
+			if got := rewriteImage(tt.image, registry, tt.rewrites); got != tt.want {
+				t.Errorf("rewriteImage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitRegistry(t *testing.T) {
+	tests := []struct {
+		image        string
+		wantRegistry string
+		wantPath     string
+	}{
+		{image: "busybox", wantRegistry: "", wantPath: "busybox"},
+		{image: "iter8/iter8-controller:v1", wantRegistry: "", wantPath: "iter8/iter8-controller:v1"},
+		{image: "quay.io/iter8/iter8-controller", wantRegistry: "quay.io", wantPath: "iter8/iter8-controller"},
+		{image: "registry:5000/iter8", wantRegistry: "registry:5000", wantPath: "iter8"},
+		{image: "localhost/iter8", wantRegistry: "localhost", wantPath: "iter8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			registry, path := splitRegistry(tt.image)
+			if registry != tt.wantRegistry || path != tt.wantPath {
+				t.Errorf("splitRegistry() = (%q, %q), want (%q, %q)", registry, path, tt.wantRegistry, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestQualifiedImage(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{image: "busybox:1.32", want: "docker.io/library/busybox:1.32"},
+		{image: "iter8/iter8-controller:v1", want: "docker.io/iter8/iter8-controller:v1"},
+		{image: "docker.io/iter8/iter8-controller:v1", want: "docker.io/iter8/iter8-controller:v1"},
+		{image: "quay.io/iter8/iter8-controller:v1", want: "quay.io/iter8/iter8-controller:v1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			if got := qualifiedImage(tt.image); got != tt.want {
+				t.Errorf("qualifiedImage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImageRepository(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{image: "iter8/iter8-controller", want: "iter8/iter8-controller"},
+		{image: "iter8/iter8-controller:v1", want: "iter8/iter8-controller"},
+		{image: "iter8/iter8-controller@sha256:abc", want: "iter8/iter8-controller"},
+		{image: "iter8/iter8-controller:v1@sha256:abc", want: "iter8/iter8-controller"},
+		{image: "registry:5000/iter8/iter8-controller", want: "registry:5000/iter8/iter8-controller"},
+		{image: "registry:5000/iter8/iter8-controller:v1", want: "registry:5000/iter8/iter8-controller"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			if got := imageRepository(tt.image); got != tt.want {
+				t.Errorf("imageRepository() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImageForIter8(t *testing.T) {
+	pin := true
+	registry := "mirror.example.com"
+	status := iter8v1alpha1.Iter8Status{
+		Images: []iter8v1alpha1.ImageStatus{{
+			Component: controllerDefaultName,
+			Image:     "mirror.example.com/iter8/iter8-controller:v1",
+			Digest:    "sha256:abc",
+		}},
+	}
+
+	tests := []struct {
+		name      string
+		spec      iter8v1alpha1.Iter8Spec
+		component string
+		image     string
+		want      string
+	}{{
+		name:      "not pinned",
+		spec:      iter8v1alpha1.Iter8Spec{ImageRegistry: &registry},
+		component: controllerDefaultName,
+		image:     "iter8/iter8-controller:v1",
+		want:      "mirror.example.com/iter8/iter8-controller:v1",
+	}, {
+		name:      "pinned to recorded digest",
+		spec:      iter8v1alpha1.Iter8Spec{ImageRegistry: &registry, PinImageDigests: &pin},
+		component: controllerDefaultName,
+		image:     "iter8/iter8-controller:v1",
+		want:      "mirror.example.com/iter8/iter8-controller@sha256:abc",
+	}, {
+		name:      "image changed since digest recorded",
+		spec:      iter8v1alpha1.Iter8Spec{ImageRegistry: &registry, PinImageDigests: &pin},
+		component: controllerDefaultName,
+		image:     "iter8/iter8-controller:v2",
+		want:      "mirror.example.com/iter8/iter8-controller:v2",
+	}, {
+		name:      "no digest recorded for component",
+		spec:      iter8v1alpha1.Iter8Spec{ImageRegistry: &registry, PinImageDigests: &pin},
+		component: analyticsDefaultName,
+		image:     "iter8/iter8-controller:v1",
+		want:      "mirror.example.com/iter8/iter8-controller:v1",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			iter8 := &iter8v1alpha1.Iter8{Spec: tt.spec, Status: status}
+			if got := imageForIter8(iter8, tt.component, tt.image); got != tt.want {
+				t.Errorf("imageForIter8() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// otherIter8s returns the Iter8 resources, other than iter8, that are not being deleted
+func (r *Iter8Reconciler) otherIter8s(iter8 *iter8v1alpha1.Iter8) ([]iter8v1alpha1.Iter8, error) {
+	list := &iter8v1alpha1.Iter8List{}
+	err := r.Client.List(context.TODO(), list)
+	if err != nil {
+		return nil, err
+	}
+	others := []iter8v1alpha1.Iter8{}
+	for _, other := range list.Items {
+		if other.UID == iter8.UID || other.GetDeletionTimestamp() != nil {
+			continue
+		}
+		others = append(others, other)
+	}
+	return others, nil
+}
+
+// isDuplicate determines whether iter8 is ignored because an older Iter8 resource manages iter8 in the same
+// namespace, or would watch the same experiments. Iter8 resources in different namespaces are independent
+// installations only if both have scope namespaces: a controller with scope cluster reconciles experiments in
+// every namespace. Only one Iter8 resource is permitted per namespace since the namespaced resources have
+// fixed names. The Duplicate condition records the outcome.
+func (r *Iter8Reconciler) isDuplicate(iter8 *iter8v1alpha1.Iter8, others []iter8v1alpha1.Iter8) bool {
+	for _, other := range others {
+		if !overlaps(other, *iter8) || !olderThan(other, *iter8) {
+			continue
+		}
+		r.Log.Info("Ignoring duplicate Iter8 resource", "name", iter8.Name, "namespace", iter8.Namespace, "existing", other.Name)
+		condition := iter8v1alpha1.Condition{
+			Type:    iter8v1alpha1.Iter8ConditionDuplicate,
+			Status:  corev1.ConditionTrue,
+			Reason:  iter8v1alpha1.Iter8ReasonDuplicateInstance,
+			Message: fmt.Sprintf("Iter8 resource %s already manages iter8 in namespace %s", other.Name, other.Namespace),
+		}
+		if other.Namespace != iter8.Namespace {
+			condition.Reason = iter8v1alpha1.Iter8ReasonOverlappingScope
+			condition.Message = fmt.Sprintf("Iter8 resource %s in namespace %s already manages iter8; "+
+				"Iter8 resources in different namespaces must have scope %s", other.Name, other.Namespace, iter8v1alpha1.ScopeNamespaces)
+		}
+		r.setCondition(iter8, condition)
+		return true
+	}
+	r.setCondition(iter8, iter8v1alpha1.Condition{
+		Type:   iter8v1alpha1.Iter8ConditionDuplicate,
+		Status: corev1.ConditionFalse,
+	})
+	return false
+}
+
+// overlaps determines whether two Iter8 resources would manage the same resources or experiments
+func overlaps(a iter8v1alpha1.Iter8, b iter8v1alpha1.Iter8) bool {
+	return a.Namespace == b.Namespace ||
+		iter8v1alpha1.GetScope(a.Spec) == iter8v1alpha1.ScopeCluster ||
+		iter8v1alpha1.GetScope(b.Spec) == iter8v1alpha1.ScopeCluster
+}
+
+// olderThan orders Iter8 resources by creation time, then name
+func olderThan(a iter8v1alpha1.Iter8, b iter8v1alpha1.Iter8) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	return a.Name < b.Name
+}
+
+// otherIter8sForIter8 maps a change to an Iter8 resource to reconcile requests for the other Iter8 resources
+// so that a duplicate takes over when the Iter8 resource in use is deleted or its scope changes
+func (r *Iter8Reconciler) otherIter8sForIter8(obj handler.MapObject) []reconcile.Request {
+	list := &iter8v1alpha1.Iter8List{}
+	err := r.Client.List(context.TODO(), list)
+	if err != nil {
+		r.Log.Error(err, "Unable to list Iter8 resources")
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, iter8 := range list.Items {
+		if iter8.Name == obj.Meta.GetName() && iter8.Namespace == obj.Meta.GetNamespace() {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: iter8.Name, Namespace: iter8.Namespace},
+		})
+	}
+	return requests
+}
@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"testing"
+
+	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
+)
+
+func TestOverlaps(t *testing.T) {
+	cluster := iter8v1alpha1.ScopeCluster
+	namespaces := iter8v1alpha1.ScopeNamespaces
+
+	tests := []struct {
+		name       string
+		namespaceA string
+		scopeA     *string
+		namespaceB string
+		scopeB     *string
+		want       bool
+	}{
+		{name: "same namespace", namespaceA: "iter8", scopeA: &namespaces, namespaceB: "iter8", scopeB: &namespaces, want: true},
+		{name: "both scope cluster by default", namespaceA: "iter8-a", namespaceB: "iter8-b", want: true},
+		{name: "one scope cluster", namespaceA: "iter8-a", scopeA: &cluster, namespaceB: "iter8-b", scopeB: &namespaces, want: true},
+		{name: "both scope namespaces", namespaceA: "iter8-a", scopeA: &namespaces, namespaceB: "iter8-b", scopeB: &namespaces, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := iter8ForTest(tt.namespaceA, "iter8", 0)
+			a.Spec.Scope = tt.scopeA
+			b := iter8ForTest(tt.namespaceB, "iter8", 1)
+			b.Spec.Scope = tt.scopeB
+			if got := overlaps(a, b); got != tt.want {
+				t.Errorf("overlaps() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
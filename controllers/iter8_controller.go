@@ -18,22 +18,25 @@ package controllers
 
 import (
 	"context"
-	"fmt"
-	"io/ioutil"
-	"regexp"
-	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	apiextensions "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/client-go/deprecated/scheme"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
 )
@@ -41,8 +44,14 @@ import (
 // Iter8Reconciler reconciles a Iter8 object
 type Iter8Reconciler struct {
 	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	// openShift is set if the cluster is OpenShift, which assigns the users pods run as
+	openShift bool
+	// routes is set if the cluster serves OpenShift Routes
+	routes bool
 }
 
 // +kubebuilder:rbac:groups=iter8.tools,resources=experiments,verbs=get;list;watch;create;update;patch;delete
@@ -56,14 +65,24 @@ type Iter8Reconciler struct {
 // +kubebuilder:rbac:groups=iter8.iter8.tools,resources=iter8s,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=iter8.iter8.tools,resources=iter8s/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=iter8.iter8.tools,resources=iter8s/finalizers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=iter8.iter8.tools,resources=metrics,verbs=get;list;watch
+// +kubebuilder:rbac:groups=iter8.iter8.tools,resources=metrics/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=route.openshift.io,resources=routes;routes/custom-host,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
 // +kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterroles,verbs=get;list;watch;create;update;patch;delete;bind
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterroles,verbs=get;list;watch;create;update;patch;delete;bind;escalate
 // +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterrolebindings,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles,verbs=get;list;watch;create;update;patch;delete;bind;escalate
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile attempts to reconcile the observed state with the desired state
 func (r *Iter8Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
@@ -99,7 +118,28 @@ func (r *Iter8Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 	// Check whether object has been deleted
 	if instance.GetDeletionTimestamp() != nil {
-		return ctrl.Result{}, r.finalize(instance)
+		return r.finalize(instance)
+	}
+
+	others, err := r.otherIter8s(instance)
+	if err != nil {
+		r.Log.Error(err, "Unable to list Iter8 resources")
+		return ctrl.Result{}, err
+	}
+	if r.isDuplicate(instance, others) {
+		return ctrl.Result{}, nil
+	}
+
+	err = r.namespacesForIter8(instance)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if iter8v1alpha1.GetAdopt(instance.Spec) {
+		err = r.adoptionForIter8(instance)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
 	}
 
 	err = r.crdsForIter8(instance)
@@ -118,6 +158,18 @@ func (r *Iter8Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	if err != nil {
 		return ctrl.Result{}, err
 	}
+	err = r.imagesForIter8(instance)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	err = r.componentsForIter8(instance)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	err = r.overridesForIter8(instance)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
 
 	// Do other things
 	r.Log.Info("Reconcile ending with nil")
@@ -127,125 +179,106 @@ func (r *Iter8Reconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 
 // SetupWithManager ...
 func (r *Iter8Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.openShift = isOpenShift(mgr.GetRESTMapper())
+	r.routes = servesKind(mgr.GetRESTMapper(), routeGVK.GroupKind())
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&iter8v1alpha1.Iter8{}).
 		Owns(&appsv1.Deployment{}).
 		Owns(&corev1.Service{}).
 		Owns(&corev1.ConfigMap{}).
 		Owns(&corev1.ServiceAccount{}).
+		Owns(&autoscalingv2beta2.HorizontalPodAutoscaler{}).
+		Owns(&policyv1beta1.PodDisruptionBudget{}).
+		Owns(&networkingv1beta1.Ingress{}).
+		Watches(&source.Kind{Type: &iter8v1alpha1.Iter8{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.otherIter8sForIter8)}).
+		// changes to the status of a Metric, made when reconciling, are ignored
+		Watches(&source.Kind{Type: &iter8v1alpha1.Metric{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.iter8sForMetric)},
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// only ConfigMaps from which metrics are loaded are of interest
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.iter8sForConfigMap)},
+			builder.WithPredicates(predicate.NewPredicateFuncs(r.isMetricsSource))).
+		Watches(&source.Kind{Type: &corev1.Namespace{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.iter8sForNamespace)}).
+		// cluster-scoped resources are mapped to their owner using labels
+		Watches(&source.Kind{Type: &rbacv1.ClusterRole{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.iter8sForClusterResource)}).
+		Watches(&source.Kind{Type: &rbacv1.ClusterRoleBinding{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(iter8ForOwnerLabels)}).
+		Watches(&source.Kind{Type: &apiextensions.CustomResourceDefinition{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.iter8sForClusterResource)}).
+		// Roles and RoleBindings in other namespaces are also mapped to their owner using labels
+		Watches(&source.Kind{Type: &rbacv1.Role{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(iter8ForOwnerLabels)}).
+		Watches(&source.Kind{Type: &rbacv1.RoleBinding{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(iter8ForOwnerLabels)}).
 		Complete(r)
 }
 
-// https://github.com/kubernetes/client-go/issues/193#issuecomment-363318588
-func (r *Iter8Reconciler) fromYaml(fileName string, iter8 *iter8v1alpha1.Iter8) error {
-	fileR, err := ioutil.ReadFile(fileName)
-	if err != nil {
-		r.Log.Error(err, fmt.Sprintf("Error reading YAML file: %s", fileName))
-		return err
-	}
-
-	acceptedK8sTypes := regexp.MustCompile(`(CustomResourceDefinition|Role|ClusterRole|RoleBinding|ClusterRoleBinding|ServiceAccount)`)
-	fileAsString := string(fileR[:])
-	sepYamlfiles := strings.Split(fileAsString, "---")
-	objects := make([]runtime.Object, 0, len(sepYamlfiles))
-
-	for _, f := range sepYamlfiles {
-		if f == "\n" || f == "" {
-			// ignore empty cases
-			continue
-		}
-
-		decode := scheme.Codecs.UniversalDeserializer().Decode
-		obj, groupVersionKind, err := decode([]byte(f), nil, nil)
-
-		if err != nil {
-			r.Log.Info(fmt.Sprintf("Error while decoding YAML object. Err was: %s", err))
-			continue
-		}
-
-		if !acceptedK8sTypes.MatchString(groupVersionKind.Kind) {
-			r.Log.Info(fmt.Sprintf("The custom-roles configMap contained K8s object types which are not supported! Skipping object with type: %s", groupVersionKind.Kind))
-		} else {
-			objects = append(objects, obj)
-		}
-
-	}
-
-	for _, obj := range objects {
-		// TODO: how can I do this?
-		// Set Iter8 instance as the owner and controller
-		// controllerutil.SetControllerReference(iter8, metav1.Object(obj), r.Scheme)
-		err = r.Client.Create(context.TODO(), obj)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
 const (
 	finalizer = "tools.iter8.iter8-op"
+
+	// uninstallRetryInterval is how often a blocked uninstall is retried
+	uninstallRetryInterval = 30 * time.Second
 )
 
-func (r *Iter8Reconciler) finalize(iter8 *iter8v1alpha1.Iter8) error {
+func (r *Iter8Reconciler) finalize(iter8 *iter8v1alpha1.Iter8) (ctrl.Result, error) {
 	r.Log.Info("finalizing")
 
 	// if being deleted
 	if iter8.GetDeletionTimestamp() != nil {
 		if contains(iter8.GetFinalizers(), finalizer) {
 
-			// Delete ClusterRoleBinding, ClusterRole, and CustomResourceDefinition
-			r.Log.Info("finalize deleting ClusterRoleBinding", "name", roleBindingDefaultName)
-			rolebinding := &rbacv1.ClusterRoleBinding{}
-			err := r.Client.Get(context.TODO(), types.NamespacedName{Name: roleBindingDefaultName}, rolebinding)
-			if err == nil {
-				err = r.Client.Delete(context.TODO(), rolebinding)
-				if err != nil {
-					return err
-				}
-			} else {
-				if !errors.IsNotFound(err) {
-					r.Log.Error(err, "Unable to delete ClusterRoleBinding")
-				}
+			experiments, err := r.listExperiments()
+			if err != nil {
+				r.Log.Error(err, "Unable to list experiments")
+				return ctrl.Result{}, err
+			}
+			others, err := r.otherIter8s(iter8)
+			if err != nil {
+				r.Log.Error(err, "Unable to list Iter8 resources")
+				return ctrl.Result{}, err
+			}
+			// experiments only block deletion of the CustomResourceDefinition, which other instances retain
+			if len(others) == 0 && r.uninstallBlocked(iter8, experiments) {
+				return ctrl.Result{RequeueAfter: uninstallRetryInterval}, nil
 			}
 
-			r.Log.Info("finalize deleting ClusterRole", "name", roleDefaultName)
-			role := &rbacv1.ClusterRole{}
-			r.Client.Get(context.TODO(), types.NamespacedName{Name: roleDefaultName}, role)
-			if err == nil {
-				err = r.Client.Delete(context.TODO(), role)
-				if err != nil {
-					return err
-				}
-			} else {
-				if !errors.IsNotFound(err) {
-					r.Log.Error(err, "Unable to delete ClusterRole")
-				}
+			// Delete Roles and RoleBindings in watched namespaces
+			err = r.pruneNamespacedRBACForIter8(iter8, []string{})
+			if err != nil {
+				r.Log.Error(err, "Unable to delete Roles and RoleBindings")
+				return ctrl.Result{}, err
 			}
 
-			r.Log.Info("finalize deleting CustomResourceDefinition", "name", "experiments.iter8.tools")
-			crd := &apiextensions.CustomResourceDefinition{}
-			r.Client.Get(context.TODO(), types.NamespacedName{Name: "experiments.iter8.tools"}, crd)
-			if err == nil {
-				err = r.Client.Delete(context.TODO(), crd)
-				if err != nil {
-					return err
-				}
-			} else {
-				if !errors.IsNotFound(err) {
-					r.Log.Error(err, "Unable to delete CustomResourceDefintion")
-				}
+			// Delete ClusterRoleBinding, ClusterRole, and CustomResourceDefinition
+			requeueAfter, done := r.teardown(iter8, experiments, len(others))
+			if !done {
+				return ctrl.Result{RequeueAfter: requeueAfter}, nil
 			}
 		}
 
 		r.Log.Info("finalize removing finalizer", "finalizer", finalizer)
 		iter8.SetFinalizers(remove(iter8.GetFinalizers(), finalizer))
-		return r.Client.Update(context.TODO(), iter8)
+		return ctrl.Result{}, r.Client.Update(context.TODO(), iter8)
 	}
 
 	// not being deleted
-	return nil
+	return ctrl.Result{}, nil
+}
+
+// setCondition records a condition in the status of the Iter8 resource
+func (r *Iter8Reconciler) setCondition(iter8 *iter8v1alpha1.Iter8, condition iter8v1alpha1.Condition) {
+	if !iter8v1alpha1.SetCondition(&iter8.Status.Conditions, condition) {
+		return
+	}
+	r.Log.Info("Updating Iter8 status", "condition", condition.Type, "status", condition.Status, "reason", condition.Reason)
+	err := r.Client.Status().Update(context.TODO(), iter8)
+	if err != nil {
+		r.Log.Error(err, "Unable to update Iter8 status")
+	}
 }
 
 // utility function determines if a str is in an array
@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Recommended labels; see https://kubernetes.io/docs/concepts/overview/working-with-objects/common-labels/
+const (
+	nameLabel      = "app.kubernetes.io/name"
+	instanceLabel  = "app.kubernetes.io/instance"
+	versionLabel   = "app.kubernetes.io/version"
+	componentLabel = "app.kubernetes.io/component"
+	partOfLabel    = "app.kubernetes.io/part-of"
+	managedByLabel = "app.kubernetes.io/managed-by"
+
+	partOfValue    = "iter8"
+	managedByValue = "iter8-operator"
+)
+
+// labelsForIter8 returns the labels common to all resources of an Iter8 instance: its common labels and
+// those identifying iter8 and the operator. Resources shared by all Iter8 instances use sharedLabels.
+func labelsForIter8(iter8 *iter8v1alpha1.Iter8) map[string]string {
+	labels := map[string]string{}
+	for k, v := range iter8.Spec.CommonLabels {
+		labels[k] = v
+	}
+	labels[partOfLabel] = partOfValue
+	labels[managedByLabel] = managedByValue
+	return labels
+}
+
+// labelsForInstance returns the labels of resources that belong to an Iter8 instance but to no single
+// component, such as its RBAC resources
+func labelsForInstance(iter8 *iter8v1alpha1.Iter8) map[string]string {
+	labels := labelsForIter8(iter8)
+	labels[instanceLabel] = iter8.Name
+	return labels
+}
+
+// labelsForComponent returns the labels of the resources of a component: those of labelsForInstance
+// and those identifying the component and its version
+func labelsForComponent(iter8 *iter8v1alpha1.Iter8, app string) map[string]string {
+	labels := labelsForInstance(iter8)
+	labels["app"] = app
+	labels[nameLabel] = app
+	switch app {
+	case controllerDefaultName:
+		labels[componentLabel] = "controller"
+		setVersionLabel(labels, iter8.Spec.Controller.Deployment.Image)
+	case analyticsDefaultName:
+		labels[componentLabel] = "analytics-engine"
+		setVersionLabel(labels, iter8.Spec.AnalyticsEngine.Deployment.Image)
+	}
+	return labels
+}
+
+// setVersionLabel sets the version label to the tag of an image, if it is a valid label value
+func setVersionLabel(labels map[string]string, image string) {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	i := strings.LastIndex(image, ":")
+	if i <= strings.LastIndex(image, "/") {
+		return
+	}
+	tag := image[i+1:]
+	if len(validation.IsValidLabelValue(tag)) == 0 {
+		labels[versionLabel] = tag
+	}
+}
+
+// setMetadata adds labels and the common annotations to an object. Labels and annotations already set
+// on the object take precedence. New maps are set so that maps shared with selectors are not modified.
+func setMetadata(iter8 *iter8v1alpha1.Iter8, obj metav1.Object, labels map[string]string) {
+	obj.SetLabels(merge(labels, obj.GetLabels()))
+	obj.SetAnnotations(merge(iter8.Spec.CommonAnnotations, obj.GetAnnotations()))
+}
+
+// setPodMetadata sets the labels of the pods of a component and adds the pod annotations
+func setPodMetadata(iter8 *iter8v1alpha1.Iter8, template *corev1.PodTemplateSpec, app string) {
+	template.Labels = merge(labelsForComponent(iter8, app), template.Labels)
+	template.Annotations = merge(iter8.Spec.PodAnnotations, template.Annotations)
+}
+
+// hasMetadata determines whether found has the labels and annotations of desired
+func hasMetadata(found metav1.Object, desired metav1.Object) bool {
+	return isSubset(toInterfaceMap(desired.GetLabels()), toInterfaceMap(found.GetLabels())) &&
+		isSubset(toInterfaceMap(desired.GetAnnotations()), toInterfaceMap(found.GetAnnotations()))
+}
+
+// mergeMetadata adds the labels and annotations of desired to found. Labels and annotations no longer
+// desired are left in place.
+func mergeMetadata(found metav1.Object, desired metav1.Object) {
+	found.SetLabels(merge(found.GetLabels(), desired.GetLabels()))
+	found.SetAnnotations(merge(found.GetAnnotations(), desired.GetAnnotations()))
+}
+
+// merge returns a new map with the entries of maps, later maps taking precedence; nil if there are none
+func merge(maps ...map[string]string) map[string]string {
+	var result map[string]string
+	for _, m := range maps {
+		for k, v := range m {
+			if nil == result {
+				result = map[string]string{}
+			}
+			result[k] = v
+		}
+	}
+	return result
+}
+
+func toInterfaceMap(m map[string]string) map[string]interface{} {
+	result := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		result[k] = v
+	}
+	return result
+}
+
+// updateMetadata adds the labels and annotations of desired to found and updates it, if any are missing
+func (r *Iter8Reconciler) updateMetadata(found runtime.Object, desired metav1.Object) error {
+	accessor, err := meta.Accessor(found)
+	if err != nil {
+		return err
+	}
+	if hasMetadata(accessor, desired) {
+		return nil
+	}
+	r.Log.Info("Updating labels and annotations", "name", accessor.GetName(), "namespace", accessor.GetNamespace())
+	mergeMetadata(accessor, desired)
+	return r.Client.Update(context.TODO(), found)
+}
@@ -2,14 +2,17 @@ package controllers
 
 import (
 	"context"
+	"reflect"
 	"strings"
 
 	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
 	"gopkg.in/yaml.v2"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
@@ -18,9 +21,16 @@ const (
 
 	controllerDefaultServicePort = int32(443)
 
+	// controllerMetricsPort is the port on which the controller serves metrics; it is also probed for health
+	controllerMetricsPort = int32(8080)
+
 	controllerDefaultDeploymentGracePeriod = int64(10)
 
 	metricsDefaultConfigMapName = "iter8config-metrics"
+	counterMetricsKey           = "counter_metrics.yaml"
+	ratioMetricsKey             = "ratio_metrics.yaml"
+	histogramMetricsKey         = "histogram_metrics.yaml"
+	gaugeMetricsKey             = "gauge_metrics.yaml"
 
 	istioNamespace  = "istio-system"
 	istioConfigMap  = "istio"
@@ -51,9 +61,19 @@ func (r *Iter8Reconciler) controllerForIter8(iter8 *iter8v1alpha1.Iter8) error {
 		r.Log.Error(err, "Failed to create controller Service")
 		return err
 	}
+	err = r.exposureForService(iter8, r.serviceForIter8Controller(iter8), iter8.Spec.Controller.Service)
+	if err != nil {
+		r.Log.Error(err, "Failed to expose controller Service")
+		return err
+	}
 	err = r.createOrUpdateDeploymentForController(iter8)
 	if err != nil {
 		r.Log.Error(err, "Failed to create controller Deployment")
+		return err
+	}
+	err = r.createOrUpdatePodDisruptionBudget(iter8, controllerDefaultName, controllerReplicas(iter8))
+	if err != nil {
+		r.Log.Error(err, "Failed to create controller PodDisruptionBudget")
 	}
 	return err
 }
@@ -70,13 +90,30 @@ func (r *Iter8Reconciler) createOrUpdateNotifierConfigMapForIter8(iter8 *iter8v1
 		return r.Client.Create(context.TODO(), cm)
 	}
 
-	// If changed, update
+	// If changed, update. The data is only updated when overridden; otherwise it is left to users.
+	if r.isOverridden(iter8, cm) && !hasData(found, cm) {
+		r.Log.Info("ConfigMap overridden, updating", "name", cm.Name)
+		found.Data = cm.Data
+		found.BinaryData = cm.BinaryData
+		mergeMetadata(found, cm)
+		return r.Client.Update(context.TODO(), found)
+	}
+	if !hasMetadata(found, cm) {
+		r.Log.Info("ConfigMap labels or annotations changed, updating", "name", cm.Name)
+		mergeMetadata(found, cm)
+		return r.Client.Update(context.TODO(), found)
+	}
 	r.Log.Info("ConfigMap already present", "name", cm.Name)
 	// cm.ResourceVersion = found.GetResourceVersion()
 	// return r.Client.Update(context.TODO(), cm)
 	return nil
 }
 
+// hasData determines whether a ConfigMap has the data of the desired one
+func hasData(found *corev1.ConfigMap, desired *corev1.ConfigMap) bool {
+	return equality.Semantic.DeepEqual(found.Data, desired.Data) && equality.Semantic.DeepEqual(found.BinaryData, desired.BinaryData)
+}
+
 func (r *Iter8Reconciler) notifierConfigMapForIter8(iter8 *iter8v1alpha1.Iter8) *corev1.ConfigMap {
 	cm := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
@@ -85,6 +122,9 @@ func (r *Iter8Reconciler) notifierConfigMapForIter8(iter8 *iter8v1alpha1.Iter8)
 		},
 	}
 
+	setMetadata(iter8, cm, labelsForComponent(iter8, controllerDefaultName))
+	r.override(iter8, cm)
+
 	// Set Iter8 instance as the owner and controller
 	controllerutil.SetControllerReference(iter8, cm, r.Scheme)
 	return cm
@@ -92,7 +132,9 @@ func (r *Iter8Reconciler) notifierConfigMapForIter8(iter8 *iter8v1alpha1.Iter8)
 
 func (r *Iter8Reconciler) createOrUpdateMetricsConfigMapForIter8(iter8 *iter8v1alpha1.Iter8) error {
 	// Desired state
-	cm := r.metricsConfigMapForIter8(iter8)
+	metrics, status := r.metricsForIter8(iter8)
+	r.updateMetricsStatus(iter8, status)
+	cm := r.metricsConfigMapForIter8(iter8, metrics)
 
 	// Get current state
 	found := &corev1.ConfigMap{}
@@ -103,9 +145,13 @@ func (r *Iter8Reconciler) createOrUpdateMetricsConfigMapForIter8(iter8 *iter8v1a
 	}
 
 	// If changed, update
+	if !reflect.DeepEqual(found.Data, cm.Data) || !hasMetadata(found, cm) {
+		r.Log.Info("ConfigMap changed, updating", "name", cm.Name)
+		found.Data = cm.Data
+		mergeMetadata(found, cm)
+		return r.Client.Update(context.TODO(), found)
+	}
 	r.Log.Info("ConfigMap already present", "name", cm.Name)
-	// cm.ResourceVersion = found.GetResourceVersion()
-	// return r.Client.Update(context.TODO(), cm)
 	return nil
 }
 
@@ -133,20 +179,24 @@ func (r *Iter8Reconciler) mixerDisabled() bool {
 	return false
 }
 
-func (r *Iter8Reconciler) metricsConfigMapForIter8(iter8 *iter8v1alpha1.Iter8) *corev1.ConfigMap {
-	counterMetrics := iter8v1alpha1.GetCounterMetrics(iter8.Spec.Metrics)
-	ratioMetrics := iter8v1alpha1.GetRatioMetrics(iter8.Spec.Metrics)
-
+// metricsConfigMapForIter8 returns the ConfigMap defining the metrics used by the analytics service;
+// metrics are those returned by metricsForIter8
+func (r *Iter8Reconciler) metricsConfigMapForIter8(iter8 *iter8v1alpha1.Iter8, metrics iter8v1alpha1.MetricsSpec) *corev1.ConfigMap {
+	canonicalUnits := iter8v1alpha1.GetCanonicalUnits(iter8.Spec.Metrics)
 	if !r.mixerDisabled() {
 		r.Log.Info("Istio mixer NOT disabled; modifying metric query templates")
-		for _, metric := range *counterMetrics {
-			metric.QueryTemplate = strings.Replace(metric.QueryTemplate, "istio_request_duration_milliseconds_sum", "istio_request_duration_seconds_sum", -1)
-			metric.QueryTemplate = strings.Replace(metric.QueryTemplate, "envoy-stats", "istio-mesh", -1)
-		}
+		metrics = rewriteForMixer(metrics)
 	} else {
 		r.Log.Info("Istio mixer disabled")
 	}
 
+	// units are normalized after rewriting since the rewritten query templates may report other units
+	metrics = normalizeMetrics(metrics, canonicalUnits)
+	counterMetrics := iter8v1alpha1.GetCounterMetrics(metrics)
+	ratioMetrics := iter8v1alpha1.GetRatioMetrics(metrics)
+	histogramMetrics := histogramMetricsWithQueryTemplates(*iter8v1alpha1.GetHistogramMetrics(metrics), canonicalUnits)
+	gaugeMetrics := iter8v1alpha1.GetGaugeMetrics(metrics)
+
 	counterMetricsYaml, err := yaml.Marshal(counterMetrics)
 	if err != nil {
 		counterMetricsYaml = make([]byte, 0)
@@ -157,22 +207,135 @@ func (r *Iter8Reconciler) metricsConfigMapForIter8(iter8 *iter8v1alpha1.Iter8) *
 		ratioMetricsYaml = make([]byte, 0)
 	}
 
+	histogramMetricsYaml, err := yaml.Marshal(histogramMetrics)
+	if err != nil {
+		histogramMetricsYaml = make([]byte, 0)
+	}
+
+	gaugeMetricsYaml, err := yaml.Marshal(gaugeMetrics)
+	if err != nil {
+		gaugeMetricsYaml = make([]byte, 0)
+	}
+
 	cm := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      metricsDefaultConfigMapName,
 			Namespace: iter8.Namespace,
 		},
 		Data: map[string]string{
-			"counter_metrics.yaml": string(counterMetricsYaml),
-			"ratio_metrics.yaml":   string(ratioMetricsYaml),
+			counterMetricsKey:   string(counterMetricsYaml),
+			ratioMetricsKey:     string(ratioMetricsYaml),
+			histogramMetricsKey: string(histogramMetricsYaml),
+			gaugeMetricsKey:     string(gaugeMetricsYaml),
 		},
 	}
 
+	setMetadata(iter8, cm, labelsForComponent(iter8, controllerDefaultName))
+	r.override(iter8, cm)
+
 	// Set Iter8 instance as the owner and controller
 	controllerutil.SetControllerReference(iter8, cm, r.Scheme)
 	return cm
 }
 
+// mixerDurationMetrics replaces the request duration metrics reported by Envoy in msec with those reported
+// by the Istio mixer in sec
+var mixerDurationMetrics = strings.NewReplacer(
+	"istio_request_duration_milliseconds_sum", "istio_request_duration_seconds_sum",
+	"istio_request_duration_milliseconds_bucket", "istio_request_duration_seconds_bucket",
+)
+
+// rewriteForMixer rewrites the query templates of counter and gauge metrics, and the bucket metrics and label
+// matchers of histogram metrics, to use the metrics reported by the Istio mixer. The mixer reports request
+// durations in seconds, so metrics in msec that use them are then in sec. Metrics are copied, not modified.
+func rewriteForMixer(metrics iter8v1alpha1.MetricsSpec) iter8v1alpha1.MetricsSpec {
+	counterMetrics := make([]iter8v1alpha1.CounterMetricSpec, 0)
+	for _, metric := range *iter8v1alpha1.GetCounterMetrics(metrics) {
+		var inSec bool
+		metric.QueryTemplate, inSec = rewriteQueryForMixer(metric.QueryTemplate)
+		if inSec && iter8v1alpha1.GetCounterMetricUnits(metric) == "msec" {
+			metric.Units = secUnits()
+		}
+		counterMetrics = append(counterMetrics, metric)
+	}
+	metrics.CounterMetrics = &counterMetrics
+
+	histogramMetrics := make([]iter8v1alpha1.HistogramMetricSpec, 0)
+	for _, metric := range *iter8v1alpha1.GetHistogramMetrics(metrics) {
+		var inSec bool
+		metric.BucketMetric, inSec = rewriteQueryForMixer(metric.BucketMetric)
+		if nil != metric.LabelMatchers {
+			labelMatchers, _ := rewriteQueryForMixer(*metric.LabelMatchers)
+			metric.LabelMatchers = &labelMatchers
+		}
+		if inSec && iter8v1alpha1.GetHistogramMetricUnits(metric) == "msec" {
+			metric.Units = secUnits()
+		}
+		histogramMetrics = append(histogramMetrics, metric)
+	}
+	metrics.HistogramMetrics = &histogramMetrics
+
+	gaugeMetrics := make([]iter8v1alpha1.GaugeMetricSpec, 0)
+	for _, metric := range *iter8v1alpha1.GetGaugeMetrics(metrics) {
+		var inSec bool
+		metric.QueryTemplate, inSec = rewriteQueryForMixer(metric.QueryTemplate)
+		if inSec && iter8v1alpha1.GetGaugeMetricUnits(metric) == "msec" {
+			metric.Units = secUnits()
+		}
+		gaugeMetrics = append(gaugeMetrics, metric)
+	}
+	metrics.GaugeMetrics = &gaugeMetrics
+	return metrics
+}
+
+// rewriteQueryForMixer rewrites a query, or part of one, to use the metrics reported by the Istio mixer.
+// Returns true if request durations in msec were replaced by those in sec.
+func rewriteQueryForMixer(query string) (string, bool) {
+	rewritten := mixerDurationMetrics.Replace(query)
+	return strings.Replace(rewritten, "envoy-stats", "istio-mesh", -1), rewritten != query
+}
+
+func secUnits() *string {
+	units := "sec"
+	return &units
+}
+
+// histogramMetric is a histogram metric as written to the metrics ConfigMap
+type histogramMetric struct {
+	iter8v1alpha1.HistogramMetricSpec `yaml:",inline"`
+	QueryTemplate                     string `yaml:"query_template"`
+}
+
+// histogramMetricsWithQueryTemplates adds the query template computing the quantile to each histogram metric,
+// converting the value to canonicalUnits if set
+func histogramMetricsWithQueryTemplates(metrics []iter8v1alpha1.HistogramMetricSpec, canonicalUnits string) []histogramMetric {
+	result := make([]histogramMetric, 0, len(metrics))
+	for _, metric := range metrics {
+		queryTemplate := histogramQueryTemplate(metric)
+		units := iter8v1alpha1.GetHistogramMetricUnits(metric)
+		if normalized := normalizedUnits(units, canonicalUnits); normalized != units {
+			queryTemplate = convertQueryTemplate(queryTemplate, units, canonicalUnits)
+			metric.Units = &normalized
+		}
+		result = append(result, histogramMetric{
+			HistogramMetricSpec: metric,
+			QueryTemplate:       queryTemplate,
+		})
+	}
+	return result
+}
+
+// histogramQueryTemplate returns a query template computing the quantile of a histogram metric, for example:
+// histogram_quantile(0.95, sum(rate(istio_request_duration_milliseconds_bucket{reporter='source'}[$interval])) by (le, $version_labels))
+func histogramQueryTemplate(metric iter8v1alpha1.HistogramMetricSpec) string {
+	selector := ""
+	if nil != metric.LabelMatchers && *metric.LabelMatchers != "" {
+		selector = "{" + *metric.LabelMatchers + "}"
+	}
+	return "histogram_quantile(" + metric.Quantile + ", sum(rate(" + metric.BucketMetric + selector +
+		"[" + iter8v1alpha1.GetHistogramMetricInterval(metric) + "])) by (le, $version_labels))"
+}
+
 func (r *Iter8Reconciler) createOrUpdateServiceAccount(iter8 *iter8v1alpha1.Iter8) error {
 	// Desired state
 	serviceAccount := r.serviceAccountForIter8Controller(iter8)
@@ -190,17 +353,59 @@ func (r *Iter8Reconciler) createOrUpdateServiceAccount(iter8 *iter8v1alpha1.Iter
 	r.Log.Info("ServiceAccount already present", "name", serviceAccount.Name)
 	// serviceAccount.ResourceVersion = found.GetResourceVersion()
 	// return r.Client.Update(context.TODO(), serviceAccount)
+	overridden := r.isOverridden(iter8, serviceAccount) && !hasServiceAccountFields(found, serviceAccount)
+	if overridden || !equality.Semantic.DeepEqual(found.ImagePullSecrets, serviceAccount.ImagePullSecrets) || !hasMetadata(found, serviceAccount) {
+		r.Log.Info("Updating ServiceAccount", "name", serviceAccount.Name)
+		found.ImagePullSecrets = serviceAccount.ImagePullSecrets
+		if overridden {
+			found.AutomountServiceAccountToken = serviceAccount.AutomountServiceAccountToken
+			found.Secrets = mergeSecrets(found.Secrets, serviceAccount.Secrets)
+		}
+		mergeMetadata(found, serviceAccount)
+		return r.Client.Update(context.TODO(), found)
+	}
 	return nil
 }
 
+// hasServiceAccountFields determines whether a ServiceAccount has the fields of the desired one that may be
+// overridden. Secrets added by the token controller are kept, so only the desired secrets must be present.
+func hasServiceAccountFields(found *corev1.ServiceAccount, desired *corev1.ServiceAccount) bool {
+	if !equality.Semantic.DeepEqual(found.AutomountServiceAccountToken, desired.AutomountServiceAccountToken) {
+		return false
+	}
+	return len(mergeSecrets(found.Secrets, desired.Secrets)) == len(found.Secrets)
+}
+
+// mergeSecrets returns secrets with those in desired that are not already present appended
+func mergeSecrets(secrets []corev1.ObjectReference, desired []corev1.ObjectReference) []corev1.ObjectReference {
+	result := append([]corev1.ObjectReference{}, secrets...)
+	for _, secret := range desired {
+		present := false
+		for _, s := range secrets {
+			if s.Name == secret.Name && s.Namespace == secret.Namespace {
+				present = true
+				break
+			}
+		}
+		if !present {
+			result = append(result, secret)
+		}
+	}
+	return result
+}
+
 func (r *Iter8Reconciler) serviceAccountForIter8Controller(iter8 *iter8v1alpha1.Iter8) *corev1.ServiceAccount {
 	sa := &corev1.ServiceAccount{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      controllerDefaultName,
 			Namespace: iter8.Namespace,
 		},
+		ImagePullSecrets: iter8.Spec.Controller.Deployment.ImagePullSecrets,
 	}
 
+	setMetadata(iter8, sa, labelsForComponent(iter8, controllerDefaultName))
+	r.override(iter8, sa)
+
 	// Set Iter8 instance as the owner and controller
 	controllerutil.SetControllerReference(iter8, sa, r.Scheme)
 	return sa
@@ -210,21 +415,7 @@ func (r *Iter8Reconciler) createOrUpdateServiceForController(iter8 *iter8v1alpha
 	// Desired state
 	service := r.serviceForIter8Controller(iter8)
 
-	// Get current state
-	found := &corev1.Service{}
-	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: service.Name, Namespace: iter8.Namespace}, found)
-	if err != nil {
-		r.Log.Info("Service not found, creating", "name", service.Name)
-		return r.Client.Create(context.TODO(), service)
-	}
-
-	// If changed, update
-	r.Log.Info("Service already present", "name", service.Name)
-	// service.ResourceVersion = found.GetResourceVersion()
-	// service.Spec = corev1.ServiceSpec{}
-	// This causes errors; not sure why
-	// return r.Client.Update(context.TODO(), service)
-	return nil
+	return r.createOrUpdateService(iter8, service)
 }
 
 func (r *Iter8Reconciler) serviceForIter8Controller(iter8 *iter8v1alpha1.Iter8) *corev1.Service {
@@ -248,6 +439,10 @@ func (r *Iter8Reconciler) serviceForIter8Controller(iter8 *iter8v1alpha1.Iter8)
 		},
 	}
 
+	setServiceExposure(svc, iter8.Spec.Controller.Service, "https")
+	setMetadata(iter8, svc, labelsForComponent(iter8, controllerDefaultName))
+	r.override(iter8, svc)
+
 	// Set Iter8 instance as the owner and controller
 	controllerutil.SetControllerReference(iter8, svc, r.Scheme)
 	return svc
@@ -257,19 +452,7 @@ func (r *Iter8Reconciler) createOrUpdateDeploymentForController(iter8 *iter8v1al
 	// Desired state
 	deployment := r.deploymentForIter8Controller(iter8)
 
-	// Get current state
-	found := &appsv1.Deployment{}
-	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: deployment.Name, Namespace: iter8.Namespace}, found)
-	if err != nil {
-		r.Log.Info("Deployment not found, creating", "name", deployment.Name)
-		return r.Client.Create(context.TODO(), deployment)
-	}
-
-	// If changed, update
-	r.Log.Info("Deployment already present", "name", deployment.Name)
-	// deployment.ResourceVersion = found.GetResourceVersion()
-	// return r.Client.Update(context.TODO(), deployment)
-	return nil
+	return r.createOrUpdateDeployment(iter8, deployment)
 }
 
 func (r *Iter8Reconciler) deploymentForIter8Controller(iter8 *iter8v1alpha1.Iter8) *appsv1.Deployment {
@@ -279,7 +462,7 @@ func (r *Iter8Reconciler) deploymentForIter8Controller(iter8 *iter8v1alpha1.Iter
 
 	serviceAccountName := controllerDefaultName
 	gracePeriod := controllerDefaultDeploymentGracePeriod
-	replicaCount := iter8v1alpha1.GetReplicaCount(iter8.Spec.Controller.Deployment)
+	replicaCount := controllerReplicas(iter8)
 
 	deploy := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
@@ -300,7 +483,7 @@ func (r *Iter8Reconciler) deploymentForIter8Controller(iter8 *iter8v1alpha1.Iter
 					ServiceAccountName:            serviceAccountName,
 					TerminationGracePeriodSeconds: &gracePeriod,
 					Containers: []corev1.Container{{
-						Image:           iter8.Spec.Controller.Deployment.Image,
+						Image:           imageForIter8(iter8, controllerDefaultName, iter8.Spec.Controller.Deployment.Image),
 						ImagePullPolicy: iter8v1alpha1.GetImagePullPolicy(iter8.Spec.Controller.Deployment),
 						Name:            controllerDefaultName,
 						Command:         []string{"/manager"},
@@ -323,8 +506,51 @@ func (r *Iter8Reconciler) deploymentForIter8Controller(iter8 *iter8v1alpha1.Iter
 		deploy.Spec.Template.Spec.Containers[0].Resources = *rsrc
 	}
 
+	// the controller is probed using its metrics endpoint
+	metricsPort := intstr.FromString("metrics")
+	deploy.Spec.Template.Spec.Containers[0].Ports = []corev1.ContainerPort{{
+		Name:          "metrics",
+		ContainerPort: controllerMetricsPort,
+	}}
+	setProbes(&deploy.Spec.Template.Spec.Containers[0], iter8.Spec.Controller.Deployment,
+		defaultProbe(metricsPort, "", 0, 3),
+		defaultProbe(metricsPort, "/metrics", 0, 3),
+		defaultProbe(metricsPort, "", 0, 30))
+	setScheduling(&deploy.Spec.Template.Spec, iter8.Spec.Controller.Deployment)
+	setAntiAffinity(&deploy.Spec.Template.Spec, controllerDefaultName, replicaCount)
+	deploy.Spec.Template.Spec.ImagePullSecrets = iter8.Spec.Controller.Deployment.ImagePullSecrets
+
+	// replicas elect a leader so that only one reconciles experiments
+	if replicaCount > 1 {
+		deploy.Spec.Template.Spec.Containers[0].Args = []string{leaderElectionFlag}
+	}
+
+	if namespaces := watchNamespaces(iter8); namespaces != "" {
+		deploy.Spec.Template.Spec.Containers[0].Env = setEnvValue(deploy.Spec.Template.Spec.Containers[0].Env, watchNamespaceEnv, namespaces)
+	}
+	setContainers(&deploy.Spec.Template.Spec, iter8.Spec.Controller.Deployment)
+	setSecurityContext(&deploy.Spec.Template, iter8.Spec.Controller.Deployment, r.openShift)
+
+	setMetadata(iter8, deploy, labelsForComponent(iter8, controllerDefaultName))
+	setPodMetadata(iter8, &deploy.Spec.Template, controllerDefaultName)
+	r.override(iter8, deploy)
+
 	// Set Iter8 instance as the owner and controller
 	controllerutil.SetControllerReference(iter8, deploy, r.Scheme)
 	return deploy
 
 }
+
+// utility function sets the value of an environment variable; an empty value removes it
+func setEnvValue(env []corev1.EnvVar, name string, value string) []corev1.EnvVar {
+	result := make([]corev1.EnvVar, 0, len(env)+1)
+	for _, e := range env {
+		if e.Name != name {
+			result = append(result, e)
+		}
+	}
+	if value != "" {
+		result = append(result, corev1.EnvVar{Name: name, Value: value})
+	}
+	return result
+}
@@ -0,0 +1,562 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// metricsForIter8 returns the metrics defined in the Iter8 resource, either inline or in the
+// ConfigMaps it refers to, merged with those defined by the Metric resources it selects (see
+// selectsMetrics). Metrics defined by the Iter8 resource take precedence; a Metric whose name is
+// already defined is rejected as a conflict. Invalid metrics are reported and left out. Whether each
+// selected Metric was accepted is returned to be recorded by updateMetricsStatus; no status is written here.
+func (r *Iter8Reconciler) metricsForIter8(iter8 *iter8v1alpha1.Iter8) (iter8v1alpha1.MetricsSpec, metricsStatus) {
+	specMetrics, definedBy, errs := r.metricsFromSpec(iter8)
+	canonicalUnits := iter8v1alpha1.GetCanonicalUnits(iter8.Spec.Metrics)
+
+	// ratio metrics are validated once all counter metrics are known so that they can refer to counter
+	// metrics defined by any Metric
+	valid, invalid := validMetrics(iter8v1alpha1.MetricsSpec{
+		CounterMetrics:   specMetrics.CounterMetrics,
+		HistogramMetrics: specMetrics.HistogramMetrics,
+		GaugeMetrics:     specMetrics.GaugeMetrics,
+	}, nil, canonicalUnits)
+	errs = append(errs, invalid...)
+
+	counterMetrics := *iter8v1alpha1.GetCounterMetrics(valid)
+	ratioMetrics := []iter8v1alpha1.RatioMetricSpec{}
+	histogramMetrics := *iter8v1alpha1.GetHistogramMetrics(valid)
+	gaugeMetrics := *iter8v1alpha1.GetGaugeMetrics(valid)
+
+	counterUnits := map[string]string{}
+	for _, metric := range counterMetrics {
+		counterUnits[metric.Name] = normalizedUnits(iter8v1alpha1.GetCounterMetricUnits(metric), canonicalUnits)
+	}
+
+	// merged returns the metrics accepted so far, with the valid ratio metrics of the Iter8 resource first
+	merged := func() (iter8v1alpha1.MetricsSpec, []string) {
+		specRatios, invalid := validMetrics(iter8v1alpha1.MetricsSpec{RatioMetrics: specMetrics.RatioMetrics}, counterUnits, canonicalUnits)
+		ratios := append(*iter8v1alpha1.GetRatioMetrics(specRatios), ratioMetrics...)
+		return iter8v1alpha1.MetricsSpec{
+			CounterMetrics:   &counterMetrics,
+			RatioMetrics:     &ratios,
+			HistogramMetrics: &histogramMetrics,
+			GaugeMetrics:     &gaugeMetrics,
+		}, append(errs, invalid...)
+	}
+
+	items, err := r.metricsSelectedBy(iter8)
+	if err != nil {
+		r.Log.Error(err, "Unable to list Metrics; using only metrics defined in Iter8 resource")
+		metrics, errs := merged()
+		return metrics, metricsStatus{errs: errs}
+	}
+
+	// oldest first so that an existing metric is not displaced by a newer one with the same name
+	sort.SliceStable(items, func(i, j int) bool {
+		if !items[i].CreationTimestamp.Equal(&items[j].CreationTimestamp) {
+			return items[i].CreationTimestamp.Before(&items[j].CreationTimestamp)
+		}
+		return metricKey(&items[i]) < metricKey(&items[j])
+	})
+
+	conditions := make(map[string]iter8v1alpha1.Condition, len(items))
+
+	// ratio metrics last so that they can refer to counter metrics defined by any Metric
+	for _, ratios := range []bool{false, true} {
+		for i := range items {
+			metric := &items[i]
+			if ratios != (nil != metric.Spec.Ratio) {
+				continue
+			}
+			err := validateMetric(metric.Spec, counterUnits, canonicalUnits)
+			if err == nil {
+				err = checkMetricConflict(iter8v1alpha1.GetMetricName(metric.Spec), definedBy)
+			}
+			conditions[metricKey(metric)] = metricCondition(err)
+			if err != nil {
+				continue
+			}
+			definedBy[iter8v1alpha1.GetMetricName(metric.Spec)] = "Metric " + metricKey(metric)
+			switch {
+			case nil != metric.Spec.Counter:
+				counterUnits[metric.Spec.Counter.Name] = normalizedUnits(iter8v1alpha1.GetCounterMetricUnits(*metric.Spec.Counter), canonicalUnits)
+				counterMetrics = append(counterMetrics, *metric.Spec.Counter)
+			case nil != metric.Spec.Ratio:
+				ratioMetrics = append(ratioMetrics, *metric.Spec.Ratio)
+			case nil != metric.Spec.Histogram:
+				histogramMetrics = append(histogramMetrics, *metric.Spec.Histogram)
+			case nil != metric.Spec.Gauge:
+				gaugeMetrics = append(gaugeMetrics, *metric.Spec.Gauge)
+			}
+		}
+	}
+
+	metrics, errs := merged()
+	return metrics, metricsStatus{
+		errs:       errs,
+		metrics:    items,
+		conditions: conditions,
+	}
+}
+
+// metricsStatus is the outcome of merging the metrics of an Iter8 resource: the problems with the metrics it
+// defines and whether each Metric resource it selects was accepted
+type metricsStatus struct {
+	errs       []string
+	metrics    []iter8v1alpha1.Metric
+	conditions map[string]iter8v1alpha1.Condition
+}
+
+// updateMetricsStatus records the outcome of merging metrics in the status of the Iter8 resource and of the
+// Metric resources it selects
+func (r *Iter8Reconciler) updateMetricsStatus(iter8 *iter8v1alpha1.Iter8, status metricsStatus) {
+	for i := range status.metrics {
+		r.updateMetricStatus(&status.metrics[i], status.conditions[metricKey(&status.metrics[i])])
+	}
+	r.setMetricsValidCondition(iter8, status.errs)
+}
+
+// metricsSelectedBy returns the Metric resources selected by an Iter8 resource
+func (r *Iter8Reconciler) metricsSelectedBy(iter8 *iter8v1alpha1.Iter8) ([]iter8v1alpha1.Metric, error) {
+	iter8s := &iter8v1alpha1.Iter8List{}
+	err := r.Client.List(context.TODO(), iter8s)
+	if err != nil {
+		return nil, err
+	}
+	metrics := &iter8v1alpha1.MetricList{}
+	err = r.Client.List(context.TODO(), metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := []iter8v1alpha1.Metric{}
+	for _, metric := range metrics.Items {
+		if selector := metricsSelector(metric.Namespace, iter8s.Items); nil != selector && selector.UID == iter8.UID {
+			selected = append(selected, metric)
+		}
+	}
+	return selected, nil
+}
+
+// selectsMetrics determines whether an Iter8 resource may select the Metric resources in a namespace: its own
+// namespace and the namespaces it onboards
+func selectsMetrics(iter8 *iter8v1alpha1.Iter8, namespace string) bool {
+	return namespace == iter8.Namespace || contains(iter8.Status.OnboardedNamespaces, namespace)
+}
+
+// metricsSelector returns the Iter8 resource that selects the Metric resources in a namespace, if any. When
+// more than one Iter8 resource may select them, the oldest does, so that each Metric belongs to a single
+// Iter8 resource.
+func metricsSelector(namespace string, iter8s []iter8v1alpha1.Iter8) *iter8v1alpha1.Iter8 {
+	var selector *iter8v1alpha1.Iter8
+	for i := range iter8s {
+		iter8 := &iter8s[i]
+		if iter8.GetDeletionTimestamp() != nil || !selectsMetrics(iter8, namespace) {
+			continue
+		}
+		if nil == selector || olderThan(*iter8, *selector) {
+			selector = iter8
+		}
+	}
+	return selector
+}
+
+// metricsFromSpec returns the metrics defined inline in the Iter8 resource followed by those loaded from
+// the ConfigMaps listed in spec.metrics.from, in order. A metric loaded from a ConfigMap is skipped if its
+// name is already defined. Also returns where each metric was defined and any problems loading metrics.
+func (r *Iter8Reconciler) metricsFromSpec(iter8 *iter8v1alpha1.Iter8) (iter8v1alpha1.MetricsSpec, map[string]string, []string) {
+	counterMetrics := append([]iter8v1alpha1.CounterMetricSpec{}, *iter8v1alpha1.GetCounterMetrics(iter8.Spec.Metrics)...)
+	ratioMetrics := append([]iter8v1alpha1.RatioMetricSpec{}, *iter8v1alpha1.GetRatioMetrics(iter8.Spec.Metrics)...)
+	histogramMetrics := append([]iter8v1alpha1.HistogramMetricSpec{}, *iter8v1alpha1.GetHistogramMetrics(iter8.Spec.Metrics)...)
+	gaugeMetrics := append([]iter8v1alpha1.GaugeMetricSpec{}, *iter8v1alpha1.GetGaugeMetrics(iter8.Spec.Metrics)...)
+
+	definedBy := map[string]string{}
+	for _, name := range metricNames(iter8.Spec.Metrics) {
+		definedBy[name] = "Iter8 " + iter8.Namespace + "/" + iter8.Name
+	}
+
+	errs := []string{}
+	for _, source := range *iter8v1alpha1.GetMetricsSources(iter8.Spec.Metrics) {
+		namespace := iter8v1alpha1.GetMetricsSourceNamespace(source, iter8.Namespace)
+		from := "ConfigMap " + namespace + "/" + source.ConfigMap
+
+		cm := &corev1.ConfigMap{}
+		err := r.Client.Get(context.TODO(), types.NamespacedName{Name: source.ConfigMap, Namespace: namespace}, cm)
+		if err != nil {
+			r.Log.Error(err, "Unable to read metrics ConfigMap", "name", source.ConfigMap, "namespace", namespace)
+			errs = append(errs, fmt.Sprintf("unable to read %s: %s", from, err))
+			continue
+		}
+		loaded, err := metricsFromConfigMap(cm)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("unable to load metrics from %s: %s", from, err))
+			continue
+		}
+
+		skip := func(name string) bool {
+			if err := checkMetricConflict(name, definedBy); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %s", from, err))
+				return true
+			}
+			definedBy[name] = from
+			return false
+		}
+		for _, metric := range *iter8v1alpha1.GetCounterMetrics(loaded) {
+			if !skip(metric.Name) {
+				counterMetrics = append(counterMetrics, metric)
+			}
+		}
+		for _, metric := range *iter8v1alpha1.GetRatioMetrics(loaded) {
+			if !skip(metric.Name) {
+				ratioMetrics = append(ratioMetrics, metric)
+			}
+		}
+		for _, metric := range *iter8v1alpha1.GetHistogramMetrics(loaded) {
+			if !skip(metric.Name) {
+				histogramMetrics = append(histogramMetrics, metric)
+			}
+		}
+		for _, metric := range *iter8v1alpha1.GetGaugeMetrics(loaded) {
+			if !skip(metric.Name) {
+				gaugeMetrics = append(gaugeMetrics, metric)
+			}
+		}
+	}
+
+	return iter8v1alpha1.MetricsSpec{
+		CounterMetrics:   &counterMetrics,
+		RatioMetrics:     &ratioMetrics,
+		HistogramMetrics: &histogramMetrics,
+		GaugeMetrics:     &gaugeMetrics,
+	}, definedBy, errs
+}
+
+// metricsFromConfigMap reads metric definitions from a ConfigMap with the same keys as the metrics ConfigMap
+func metricsFromConfigMap(cm *corev1.ConfigMap) (iter8v1alpha1.MetricsSpec, error) {
+	metrics := iter8v1alpha1.MetricsSpec{
+		CounterMetrics:   &[]iter8v1alpha1.CounterMetricSpec{},
+		RatioMetrics:     &[]iter8v1alpha1.RatioMetricSpec{},
+		HistogramMetrics: &[]iter8v1alpha1.HistogramMetricSpec{},
+		GaugeMetrics:     &[]iter8v1alpha1.GaugeMetricSpec{},
+	}
+	for key, into := range map[string]interface{}{
+		counterMetricsKey:   metrics.CounterMetrics,
+		ratioMetricsKey:     metrics.RatioMetrics,
+		histogramMetricsKey: metrics.HistogramMetrics,
+		gaugeMetricsKey:     metrics.GaugeMetrics,
+	} {
+		data, ok := cm.Data[key]
+		if !ok {
+			continue
+		}
+		if err := yaml.Unmarshal([]byte(data), into); err != nil {
+			return metrics, fmt.Errorf("%s: %s", key, err)
+		}
+	}
+	return metrics, nil
+}
+
+// validMetrics returns the valid metrics defined in the Iter8 resource and the problems with the others, which
+// are left out. Ratio metrics may refer to counter metrics in counterUnits.
+func validMetrics(metrics iter8v1alpha1.MetricsSpec, counterUnits map[string]string, canonicalUnits string) (iter8v1alpha1.MetricsSpec, []string) {
+	errs := []string{}
+	counterMetrics := []iter8v1alpha1.CounterMetricSpec{}
+	for _, metric := range *iter8v1alpha1.GetCounterMetrics(metrics) {
+		if err := validateCounterMetric(metric); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		counterMetrics = append(counterMetrics, metric)
+	}
+	ratioMetrics := []iter8v1alpha1.RatioMetricSpec{}
+	for _, metric := range *iter8v1alpha1.GetRatioMetrics(metrics) {
+		if err := validateRatioMetric(metric, counterUnits, canonicalUnits); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		ratioMetrics = append(ratioMetrics, metric)
+	}
+	histogramMetrics := []iter8v1alpha1.HistogramMetricSpec{}
+	for _, metric := range *iter8v1alpha1.GetHistogramMetrics(metrics) {
+		if err := validateHistogramMetric(metric); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		histogramMetrics = append(histogramMetrics, metric)
+	}
+	gaugeMetrics := []iter8v1alpha1.GaugeMetricSpec{}
+	for _, metric := range *iter8v1alpha1.GetGaugeMetrics(metrics) {
+		if err := validateGaugeMetric(metric); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		gaugeMetrics = append(gaugeMetrics, metric)
+	}
+	return iter8v1alpha1.MetricsSpec{
+		CounterMetrics:   &counterMetrics,
+		RatioMetrics:     &ratioMetrics,
+		HistogramMetrics: &histogramMetrics,
+		GaugeMetrics:     &gaugeMetrics,
+	}, errs
+}
+
+func (r *Iter8Reconciler) setMetricsValidCondition(iter8 *iter8v1alpha1.Iter8, errs []string) {
+	condition := iter8v1alpha1.Condition{
+		Type:   iter8v1alpha1.Iter8ConditionMetricsValid,
+		Status: corev1.ConditionTrue,
+		Reason: iter8v1alpha1.Iter8ReasonMetricsValid,
+	}
+	if len(errs) > 0 {
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = iter8v1alpha1.Iter8ReasonInvalidMetrics
+		condition.Message = strings.Join(errs, "; ")
+	}
+	r.setCondition(iter8, condition)
+}
+
+// metricNames returns the names of all metrics in a MetricsSpec
+func metricNames(metrics iter8v1alpha1.MetricsSpec) []string {
+	names := []string{}
+	for _, metric := range *iter8v1alpha1.GetCounterMetrics(metrics) {
+		names = append(names, metric.Name)
+	}
+	for _, metric := range *iter8v1alpha1.GetRatioMetrics(metrics) {
+		names = append(names, metric.Name)
+	}
+	for _, metric := range *iter8v1alpha1.GetHistogramMetrics(metrics) {
+		names = append(names, metric.Name)
+	}
+	for _, metric := range *iter8v1alpha1.GetGaugeMetrics(metrics) {
+		names = append(names, metric.Name)
+	}
+	return names
+}
+
+// validateMetric validates the metric defined by a Metric resource
+func validateMetric(metric iter8v1alpha1.MetricSpec, counterUnits map[string]string, canonicalUnits string) error {
+	if err := checkMetricKind(metric); err != nil {
+		return err
+	}
+	switch {
+	case nil != metric.Counter:
+		return validateCounterMetric(*metric.Counter)
+	case nil != metric.Ratio:
+		return validateRatioMetric(*metric.Ratio, counterUnits, canonicalUnits)
+	case nil != metric.Histogram:
+		return validateHistogramMetric(*metric.Histogram)
+	default:
+		return validateGaugeMetric(*metric.Gauge)
+	}
+}
+
+// checkMetricKind checks that a Metric resource defines exactly one metric
+func checkMetricKind(metric iter8v1alpha1.MetricSpec) error {
+	defined := 0
+	for _, set := range []bool{nil != metric.Counter, nil != metric.Ratio, nil != metric.Histogram, nil != metric.Gauge} {
+		if set {
+			defined++
+		}
+	}
+	if defined != 1 {
+		return fmt.Errorf("exactly one of counter, ratio, histogram or gauge must be specified")
+	}
+	return nil
+}
+
+func validateCounterMetric(metric iter8v1alpha1.CounterMetricSpec) error {
+	if metric.Name == "" {
+		return fmt.Errorf("counter metric has no name")
+	}
+	if metric.QueryTemplate == "" {
+		return fmt.Errorf("counter metric %s has no query_template", metric.Name)
+	}
+	if err := validateQueryTemplate(metric.QueryTemplate); err != nil {
+		return fmt.Errorf("counter metric %s: %s", metric.Name, err)
+	}
+	return nil
+}
+
+// validateRatioMetric checks that the numerator and denominator of a ratio metric are known
+// counter metrics with compatible units. counterUnits maps the name of each counter metric
+// to its units after normalization.
+func validateRatioMetric(metric iter8v1alpha1.RatioMetricSpec, counterUnits map[string]string, canonicalUnits string) error {
+	if metric.Name == "" {
+		return fmt.Errorf("ratio metric has no name")
+	}
+	numeratorUnits, ok := counterUnits[metric.Numerator]
+	if !ok {
+		return fmt.Errorf("ratio metric %s: numerator %s is not a known counter metric", metric.Name, metric.Numerator)
+	}
+	denominatorUnits, ok := counterUnits[metric.Denominator]
+	if !ok {
+		return fmt.Errorf("ratio metric %s: denominator %s is not a known counter metric", metric.Name, metric.Denominator)
+	}
+	units, err := ratioUnits(numeratorUnits, denominatorUnits)
+	if err != nil {
+		return fmt.Errorf("ratio metric %s: %s", metric.Name, err)
+	}
+	if nil != metric.Units && normalizedUnits(*metric.Units, canonicalUnits) != units {
+		return fmt.Errorf("ratio metric %s: units %s do not match units %q of %s / %s", metric.Name, *metric.Units, units, metric.Numerator, metric.Denominator)
+	}
+	return nil
+}
+
+func validateHistogramMetric(metric iter8v1alpha1.HistogramMetricSpec) error {
+	if metric.Name == "" {
+		return fmt.Errorf("histogram metric has no name")
+	}
+	if metric.BucketMetric == "" {
+		return fmt.Errorf("histogram metric %s has no bucket_metric", metric.Name)
+	}
+	quantile, err := strconv.ParseFloat(metric.Quantile, 64)
+	if err != nil || quantile < 0 || quantile > 1 {
+		return fmt.Errorf("histogram metric %s: quantile %q is not a number between 0 and 1", metric.Name, metric.Quantile)
+	}
+	if _, err := parseQueryTemplate(histogramQueryTemplate(metric)); err != nil {
+		return fmt.Errorf("histogram metric %s: %s", metric.Name, err)
+	}
+	return nil
+}
+
+func validateGaugeMetric(metric iter8v1alpha1.GaugeMetricSpec) error {
+	if metric.Name == "" {
+		return fmt.Errorf("gauge metric has no name")
+	}
+	if metric.QueryTemplate == "" {
+		return fmt.Errorf("gauge metric %s has no query_template", metric.Name)
+	}
+	if err := validateQueryTemplate(metric.QueryTemplate); err != nil {
+		return fmt.Errorf("gauge metric %s: %s", metric.Name, err)
+	}
+	return nil
+}
+
+// metricConflictError is returned when a metric name is already defined elsewhere
+type metricConflictError struct {
+	name      string
+	definedBy string
+}
+
+func (e *metricConflictError) Error() string {
+	return fmt.Sprintf("metric %s is already defined by %s", e.name, e.definedBy)
+}
+
+func checkMetricConflict(name string, definedBy map[string]string) error {
+	if by, ok := definedBy[name]; ok {
+		return &metricConflictError{name: name, definedBy: by}
+	}
+	return nil
+}
+
+func metricCondition(err error) iter8v1alpha1.Condition {
+	if err == nil {
+		return iter8v1alpha1.Condition{
+			Type:    iter8v1alpha1.MetricConditionAccepted,
+			Status:  corev1.ConditionTrue,
+			Reason:  iter8v1alpha1.MetricReasonMerged,
+			Message: "Metric merged into " + metricsDefaultConfigMapName,
+		}
+	}
+	reason := iter8v1alpha1.MetricReasonInvalid
+	if _, ok := err.(*metricConflictError); ok {
+		reason = iter8v1alpha1.MetricReasonConflict
+	}
+	return iter8v1alpha1.Condition{
+		Type:    iter8v1alpha1.MetricConditionAccepted,
+		Status:  corev1.ConditionFalse,
+		Reason:  reason,
+		Message: err.Error(),
+	}
+}
+
+func (r *Iter8Reconciler) updateMetricStatus(metric *iter8v1alpha1.Metric, condition iter8v1alpha1.Condition) {
+	if !iter8v1alpha1.SetCondition(&metric.Status.Conditions, condition) {
+		return
+	}
+	r.Log.Info("Updating Metric status", "metric", metricKey(metric), "reason", condition.Reason)
+	err := r.Client.Status().Update(context.TODO(), metric)
+	if err != nil {
+		r.Log.Error(err, "Unable to update Metric status", "metric", metricKey(metric))
+	}
+}
+
+func metricKey(metric *iter8v1alpha1.Metric) string {
+	return metric.Namespace + "/" + metric.Name
+}
+
+// iter8sForMetric maps a change to a Metric to a reconcile request for the Iter8 instance that selects it
+func (r *Iter8Reconciler) iter8sForMetric(obj handler.MapObject) []reconcile.Request {
+	iter8s := &iter8v1alpha1.Iter8List{}
+	err := r.Client.List(context.TODO(), iter8s)
+	if err != nil {
+		r.Log.Error(err, "Unable to list Iter8 resources")
+		return nil
+	}
+
+	selector := metricsSelector(obj.Meta.GetNamespace(), iter8s.Items)
+	if nil == selector {
+		return nil
+	}
+	return []reconcile.Request{{
+		NamespacedName: types.NamespacedName{Name: selector.Name, Namespace: selector.Namespace},
+	}}
+}
+
+// iter8sForConfigMap maps a change to a ConfigMap to a reconcile request for each Iter8 instance that loads metrics from it
+func (r *Iter8Reconciler) iter8sForConfigMap(obj handler.MapObject) []reconcile.Request {
+	requests := []reconcile.Request{}
+	for _, iter8 := range r.iter8sForMetricsSource(obj.Meta) {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: iter8.Name, Namespace: iter8.Namespace},
+		})
+	}
+	return requests
+}
+
+// isMetricsSource determines whether any Iter8 instance loads metrics from a ConfigMap. Used as a predicate
+// so that changes to other ConfigMaps are ignored.
+func (r *Iter8Reconciler) isMetricsSource(configMap metav1.Object, _ runtime.Object) bool {
+	return len(r.iter8sForMetricsSource(configMap)) > 0
+}
+
+// iter8sForMetricsSource returns the Iter8 instances that load metrics from a ConfigMap
+func (r *Iter8Reconciler) iter8sForMetricsSource(configMap metav1.Object) []iter8v1alpha1.Iter8 {
+	iter8s := &iter8v1alpha1.Iter8List{}
+	err := r.Client.List(context.TODO(), iter8s)
+	if err != nil {
+		r.Log.Error(err, "Unable to list Iter8 resources")
+		return nil
+	}
+
+	result := []iter8v1alpha1.Iter8{}
+	for _, iter8 := range iter8s.Items {
+		if loadsMetricsFrom(&iter8, configMap) {
+			result = append(result, iter8)
+		}
+	}
+	return result
+}
+
+// loadsMetricsFrom determines whether spec.metrics.from of iter8 refers to a ConfigMap
+func loadsMetricsFrom(iter8 *iter8v1alpha1.Iter8, configMap metav1.Object) bool {
+	for _, source := range *iter8v1alpha1.GetMetricsSources(iter8.Spec.Metrics) {
+		if source.ConfigMap == configMap.GetName() &&
+			iter8v1alpha1.GetMetricsSourceNamespace(source, iter8.Namespace) == configMap.GetNamespace() {
+			return true
+		}
+	}
+	return false
+}
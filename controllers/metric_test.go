@@ -0,0 +1,303 @@
+package controllers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const testQueryTemplate = "sum(increase(istio_requests_total[$interval])) by ($version_labels)"
+
+// iter8ForTest returns an Iter8 resource created at a time in minutes, onboarding namespaces
+func iter8ForTest(namespace string, name string, minute int, onboarded ...string) iter8v1alpha1.Iter8 {
+	return iter8v1alpha1.Iter8{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         namespace,
+			UID:               types.UID(namespace + "/" + name),
+			CreationTimestamp: metav1.NewTime(time.Date(2020, 1, 1, 0, minute, 0, 0, time.UTC)),
+		},
+		Status: iter8v1alpha1.Iter8Status{OnboardedNamespaces: onboarded},
+	}
+}
+
+func TestMetricsSelector(t *testing.T) {
+	deleted := iter8ForTest("iter8-old", "iter8", 0, "apps")
+	now := metav1.Now()
+	deleted.DeletionTimestamp = &now
+
+	iter8s := []iter8v1alpha1.Iter8{
+		iter8ForTest("iter8-b", "iter8", 2, "apps"),
+		iter8ForTest("iter8-a", "iter8", 1, "apps", "other"),
+		deleted,
+	}
+
+	tests := []struct {
+		namespace string
+		want      string
+	}{
+		{namespace: "apps", want: "iter8-a/iter8"},
+		{namespace: "other", want: "iter8-a/iter8"},
+		{namespace: "iter8-b", want: "iter8-b/iter8"},
+		{namespace: "iter8-old", want: ""},
+		{namespace: "unknown", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.namespace, func(t *testing.T) {
+			got := ""
+			if selector := metricsSelector(tt.namespace, iter8s); nil != selector {
+				got = selector.Namespace + "/" + selector.Name
+			}
+			if got != tt.want {
+				t.Errorf("metricsSelector() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadsMetricsFrom(t *testing.T) {
+	other := "metrics"
+	iter8 := iter8ForTest("iter8", "iter8", 0)
+	iter8.Spec.Metrics.From = &[]iter8v1alpha1.MetricsSourceSpec{
+		{ConfigMap: "local"},
+		{ConfigMap: "shared", Namespace: &other},
+	}
+
+	tests := []struct {
+		namespace string
+		name      string
+		want      bool
+	}{
+		{namespace: "iter8", name: "local", want: true},
+		{namespace: "metrics", name: "shared", want: true},
+		{namespace: "metrics", name: "local", want: false},
+		{namespace: "iter8", name: "shared", want: false},
+		{namespace: "iter8", name: "iter8-config", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.namespace+"/"+tt.name, func(t *testing.T) {
+			configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: tt.name, Namespace: tt.namespace}}
+			if got := loadsMetricsFrom(&iter8, configMap); got != tt.want {
+				t.Errorf("loadsMetricsFrom() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRatioMetric(t *testing.T) {
+	counterUnits := map[string]string{"requests": "", "errors": "", "duration": "msec"}
+
+	tests := []struct {
+		name           string
+		metric         iter8v1alpha1.RatioMetricSpec
+		canonicalUnits string
+		wantErr        bool
+	}{{
+		name:   "no units",
+		metric: iter8v1alpha1.RatioMetricSpec{Name: "error_rate", Numerator: "errors", Denominator: "requests"},
+	}, {
+		name:   "units of the numerator",
+		metric: iter8v1alpha1.RatioMetricSpec{Name: "mean_latency", Numerator: "duration", Denominator: "requests", Units: stringPtr("msec")},
+	}, {
+		name:           "specified units normalized",
+		metric:         iter8v1alpha1.RatioMetricSpec{Name: "mean_latency", Numerator: "duration", Denominator: "requests", Units: stringPtr("sec")},
+		canonicalUnits: "msec",
+	}, {
+		name:    "units do not match",
+		metric:  iter8v1alpha1.RatioMetricSpec{Name: "mean_latency", Numerator: "duration", Denominator: "requests", Units: stringPtr("sec")},
+		wantErr: true,
+	}, {
+		name:    "no name",
+		metric:  iter8v1alpha1.RatioMetricSpec{Numerator: "errors", Denominator: "requests"},
+		wantErr: true,
+	}, {
+		name:    "unknown numerator",
+		metric:  iter8v1alpha1.RatioMetricSpec{Name: "rate", Numerator: "unknown", Denominator: "requests"},
+		wantErr: true,
+	}, {
+		name:    "unknown denominator",
+		metric:  iter8v1alpha1.RatioMetricSpec{Name: "rate", Numerator: "errors", Denominator: "unknown"},
+		wantErr: true,
+	}, {
+		name:    "incompatible units",
+		metric:  iter8v1alpha1.RatioMetricSpec{Name: "rate", Numerator: "requests", Denominator: "duration"},
+		wantErr: true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRatioMetric(tt.metric, counterUnits, tt.canonicalUnits)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateRatioMetric() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMetricCondition(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus corev1.ConditionStatus
+		wantReason string
+	}{
+		{name: "accepted", err: nil, wantStatus: corev1.ConditionTrue, wantReason: iter8v1alpha1.MetricReasonMerged},
+		{name: "conflict", err: checkMetricConflict("requests", map[string]string{"requests": "Iter8 resource"}),
+			wantStatus: corev1.ConditionFalse, wantReason: iter8v1alpha1.MetricReasonConflict},
+		{name: "invalid", err: fmt.Errorf("counter metric has no name"), wantStatus: corev1.ConditionFalse, wantReason: iter8v1alpha1.MetricReasonInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition := metricCondition(tt.err)
+			if condition.Status != tt.wantStatus || condition.Reason != tt.wantReason {
+				t.Errorf("metricCondition() = %s/%s, want %s/%s", condition.Status, condition.Reason, tt.wantStatus, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestMetricsFromConfigMap(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      map[string]string
+		wantNames []string
+		wantErr   bool
+	}{{
+		name: "all kinds",
+		data: map[string]string{
+			counterMetricsKey:   "- name: requests\n  query_template: q\n",
+			ratioMetricsKey:     "- name: error_rate\n  numerator: errors\n  denominator: requests\n",
+			histogramMetricsKey: "- name: p95\n  bucket_metric: b\n  quantile: \"0.95\"\n",
+			gaugeMetricsKey:     "- name: cpu\n  query_template: q\n",
+		},
+		wantNames: []string{"requests", "error_rate", "p95", "cpu"},
+	}, {
+		name:      "missing keys",
+		data:      map[string]string{counterMetricsKey: "- name: requests\n  query_template: q\n"},
+		wantNames: []string{"requests"},
+	}, {
+		name:      "empty",
+		wantNames: []string{},
+	}, {
+		name:    "invalid YAML",
+		data:    map[string]string{ratioMetricsKey: "name: [error_rate"},
+		wantErr: true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics, err := metricsFromConfigMap(&corev1.ConfigMap{Data: tt.data})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("metricsFromConfigMap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := metricNames(metrics); !stringsEqualUnordered(got, tt.wantNames) {
+				t.Errorf("metricsFromConfigMap() names = %v, want %v", got, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestMetricsForIter8(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = iter8v1alpha1.AddToScheme(scheme)
+
+	iter8 := iter8ForTest("iter8", "iter8", 0, "apps")
+	iter8.Spec.Metrics = iter8v1alpha1.MetricsSpec{
+		CounterMetrics: &[]iter8v1alpha1.CounterMetricSpec{
+			{Name: "requests", QueryTemplate: testQueryTemplate},
+			// invalid, so left out
+			{Name: "not_aggregated", QueryTemplate: "increase(istio_requests_total[$interval])"},
+		},
+		// refers to a counter metric defined by a Metric
+		RatioMetrics: &[]iter8v1alpha1.RatioMetricSpec{{Name: "spec_error_rate", Numerator: "errors", Denominator: "requests"}},
+	}
+	metric := func(namespace string, name string, minute int, spec iter8v1alpha1.MetricSpec) *iter8v1alpha1.Metric {
+		return &iter8v1alpha1.Metric{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         namespace,
+				CreationTimestamp: metav1.NewTime(time.Date(2020, 1, 1, 0, minute, 0, 0, time.UTC)),
+			},
+			Spec: spec,
+		}
+	}
+
+	objects := []runtime.Object{
+		&iter8,
+		// accepted, and referred to by a ratio metric
+		metric("apps", "errors", 1, iter8v1alpha1.MetricSpec{Counter: &iter8v1alpha1.CounterMetricSpec{Name: "errors", QueryTemplate: testQueryTemplate}}),
+		metric("iter8", "error-rate", 2, iter8v1alpha1.MetricSpec{Ratio: &iter8v1alpha1.RatioMetricSpec{Name: "error_rate", Numerator: "errors", Denominator: "requests"}}),
+		// already defined by the Iter8 resource
+		metric("apps", "requests", 3, iter8v1alpha1.MetricSpec{Counter: &iter8v1alpha1.CounterMetricSpec{Name: "requests", QueryTemplate: testQueryTemplate}}),
+		// already defined by an older Metric
+		metric("iter8", "errors-again", 4, iter8v1alpha1.MetricSpec{Counter: &iter8v1alpha1.CounterMetricSpec{Name: "errors", QueryTemplate: testQueryTemplate}}),
+		// invalid
+		metric("apps", "invalid", 5, iter8v1alpha1.MetricSpec{Gauge: &iter8v1alpha1.GaugeMetricSpec{Name: "cpu"}}),
+		// in a namespace not selected
+		metric("other", "latency", 6, iter8v1alpha1.MetricSpec{Counter: &iter8v1alpha1.CounterMetricSpec{Name: "latency", QueryTemplate: testQueryTemplate}}),
+	}
+	r := &Iter8Reconciler{
+		Client: fake.NewFakeClientWithScheme(scheme, objects...),
+		Log:    logf.Log.WithName("test"),
+		Scheme: scheme,
+	}
+
+	merged, status := r.metricsForIter8(&iter8)
+
+	if got, want := metricNames(merged), []string{"requests", "errors", "spec_error_rate", "error_rate"}; !stringsEqualUnordered(got, want) {
+		t.Errorf("merged metrics = %v, want %v", got, want)
+	}
+	if len(status.errs) != 1 {
+		t.Errorf("errs = %v, want one for not_aggregated", status.errs)
+	}
+	if len(status.metrics) != 5 {
+		t.Errorf("selected %d Metrics, want 5", len(status.metrics))
+	}
+
+	wantReasons := map[string]string{
+		"apps/errors":        iter8v1alpha1.MetricReasonMerged,
+		"iter8/error-rate":   iter8v1alpha1.MetricReasonMerged,
+		"apps/requests":      iter8v1alpha1.MetricReasonConflict,
+		"iter8/errors-again": iter8v1alpha1.MetricReasonConflict,
+		"apps/invalid":       iter8v1alpha1.MetricReasonInvalid,
+	}
+	for key, want := range wantReasons {
+		if got := status.conditions[key].Reason; got != want {
+			t.Errorf("Metric %s reason = %q, want %q", key, got, want)
+		}
+	}
+}
+
+// stringsEqualUnordered determines whether a and b have the same elements, in any order
+func stringsEqualUnordered(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	counts := map[string]int{}
+	for _, s := range a {
+		counts[s]++
+	}
+	for _, s := range b {
+		counts[s]--
+		if counts[s] < 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+
+	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	istioInjectionLabel = "istio-injection"
+	istioInjectionValue = "enabled"
+)
+
+// namespacesForIter8 onboards the namespaces listed in the Iter8 resource or selected by its
+// experimentNamespaceSelector and records them in status
+func (r *Iter8Reconciler) namespacesForIter8(iter8 *iter8v1alpha1.Iter8) error {
+	onboarded := map[string]bool{}
+	for _, namespace := range iter8.Spec.Namespaces {
+		onboarded[namespace] = true
+	}
+
+	if nil != iter8.Spec.ExperimentNamespaceSelector {
+		selector, err := metav1.LabelSelectorAsSelector(iter8.Spec.ExperimentNamespaceSelector)
+		if err != nil {
+			r.Log.Error(err, "Invalid experimentNamespaceSelector")
+			return err
+		}
+		namespaces := &corev1.NamespaceList{}
+		err = r.Client.List(context.TODO(), namespaces, client.MatchingLabelsSelector{Selector: selector})
+		if err != nil {
+			return err
+		}
+		for _, namespace := range namespaces.Items {
+			if namespace.GetDeletionTimestamp() == nil {
+				onboarded[namespace.Name] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(onboarded))
+	for namespace := range onboarded {
+		names = append(names, namespace)
+	}
+	sort.Strings(names)
+
+	if iter8v1alpha1.GetIstioInjection(iter8.Spec) {
+		for _, namespace := range names {
+			err := r.labelForIstioInjection(namespace)
+			if err != nil {
+				r.Log.Error(err, "Unable to label namespace for Istio injection", "namespace", namespace)
+				return err
+			}
+		}
+	}
+
+	if !equality.Semantic.DeepEqual(names, iter8.Status.OnboardedNamespaces) {
+		r.Log.Info("Onboarded namespaces changed", "namespaces", names)
+		iter8.Status.OnboardedNamespaces = names
+		err := r.Client.Status().Update(context.TODO(), iter8)
+		if err != nil {
+			r.Log.Error(err, "Unable to update Iter8 status")
+		}
+	}
+	return nil
+}
+
+// labelForIstioInjection enables Istio sidecar injection in a namespace
+func (r *Iter8Reconciler) labelForIstioInjection(name string) error {
+	namespace := &corev1.Namespace{}
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: name}, namespace)
+	if err != nil {
+		return err
+	}
+	if namespace.Labels[istioInjectionLabel] == istioInjectionValue {
+		return nil
+	}
+	r.Log.Info("Labeling namespace for Istio injection", "namespace", name)
+	if nil == namespace.Labels {
+		namespace.Labels = map[string]string{}
+	}
+	namespace.Labels[istioInjectionLabel] = istioInjectionValue
+	return r.Client.Update(context.TODO(), namespace)
+}
+
+// iter8sForNamespace maps a change to a Namespace to reconcile requests for the Iter8 resources that
+// select namespaces by label
+func (r *Iter8Reconciler) iter8sForNamespace(obj handler.MapObject) []reconcile.Request {
+	list := &iter8v1alpha1.Iter8List{}
+	err := r.Client.List(context.TODO(), list)
+	if err != nil {
+		r.Log.Error(err, "Unable to list Iter8 resources")
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, iter8 := range list.Items {
+		if nil == iter8.Spec.ExperimentNamespaceSelector && !contains(iter8.Status.OnboardedNamespaces, obj.Meta.GetName()) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: iter8.Name, Namespace: iter8.Namespace},
+		})
+	}
+	return requests
+}
@@ -0,0 +1,210 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/yaml"
+)
+
+// override applies the overrides in the Iter8 resource that match the kind and name of a generated object.
+// An override that cannot be applied leaves the object unchanged; overridesForIter8 reports it.
+func (r *Iter8Reconciler) override(iter8 *iter8v1alpha1.Iter8, obj runtime.Object) {
+	r.applyOverrides(iter8.Spec.Overrides, obj)
+}
+
+// applyOverrides applies, in order, the overrides that match the kind and name of an object. Returns the
+// outcome of each override applied, by index: nil if applied, the error if not.
+func (r *Iter8Reconciler) applyOverrides(overrides []iter8v1alpha1.OverrideSpec, obj runtime.Object) map[int]error {
+	results := map[int]error{}
+	if len(overrides) == 0 {
+		return results
+	}
+	kinds, _, err := r.Scheme.ObjectKinds(obj)
+	if err != nil || len(kinds) == 0 {
+		return results
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return results
+	}
+
+	for i, override := range overrides {
+		if override.Kind != kinds[0].Kind || override.Name != accessor.GetName() {
+			continue
+		}
+		err = patchObject(obj, override)
+		if err != nil {
+			err = fmt.Errorf("%s %s: %s", override.Kind, override.Name, err)
+		}
+		results[i] = err
+	}
+	return results
+}
+
+// isOverridden determines whether any override of the Iter8 resource matches the kind and name of an object.
+// Objects whose contents are otherwise left to users once created are updated when overridden, so that the
+// patched fields are applied.
+func (r *Iter8Reconciler) isOverridden(iter8 *iter8v1alpha1.Iter8, obj runtime.Object) bool {
+	kinds, _, err := r.Scheme.ObjectKinds(obj)
+	if err != nil || len(kinds) == 0 {
+		return false
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return false
+	}
+	for _, override := range iter8.Spec.Overrides {
+		if override.Kind == kinds[0].Kind && override.Name == accessor.GetName() {
+			return true
+		}
+	}
+	return false
+}
+
+// patchObject applies an override to a typed object
+func patchObject(obj runtime.Object, override iter8v1alpha1.OverrideSpec) error {
+	patch, err := yaml.YAMLToJSON([]byte(override.Patch))
+	if err != nil {
+		return err
+	}
+	original, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	var patched []byte
+	switch iter8v1alpha1.GetPatchType(override) {
+	case iter8v1alpha1.PatchTypeMerge:
+		patched, err = jsonpatch.MergePatch(original, patch)
+	case iter8v1alpha1.PatchTypeJSON:
+		var operations jsonpatch.Patch
+		operations, err = jsonpatch.DecodePatch(patch)
+		if err == nil {
+			patched, err = operations.Apply(original)
+		}
+	default:
+		patched, err = strategicpatch.StrategicMergePatch(original, patch, obj)
+	}
+	if err != nil {
+		return err
+	}
+
+	// decode into a copy so that a patch that does not decode leaves the object unchanged
+	result := reflect.New(reflect.TypeOf(obj).Elem())
+	err = json.Unmarshal(patched, result.Interface())
+	if err != nil {
+		return err
+	}
+	reflect.ValueOf(obj).Elem().Set(result.Elem())
+	return nil
+}
+
+// overridesForIter8 records in status whether all overrides were applied to the generated objects. Returns an
+// error if any override was not applied.
+func (r *Iter8Reconciler) overridesForIter8(iter8 *iter8v1alpha1.Iter8) error {
+	if len(iter8.Spec.Overrides) == 0 && nil == iter8v1alpha1.GetCondition(iter8.Status.Conditions, iter8v1alpha1.Iter8ConditionOverridesApplied) {
+		return nil
+	}
+
+	results := r.overrideResultsForIter8(iter8)
+	failures := []string{}
+	for i, override := range iter8.Spec.Overrides {
+		err, matched := results[i]
+		switch {
+		case !matched:
+			failures = append(failures, fmt.Sprintf("%s %s: matches no generated object", override.Kind, override.Name))
+		case err != nil:
+			r.Log.Error(err, "Unable to apply override", "kind", override.Kind, "name", override.Name)
+			failures = append(failures, err.Error())
+		}
+	}
+
+	condition := iter8v1alpha1.Condition{
+		Type:   iter8v1alpha1.Iter8ConditionOverridesApplied,
+		Status: corev1.ConditionTrue,
+		Reason: iter8v1alpha1.Iter8ReasonOverridesApplied,
+	}
+	if len(failures) > 0 {
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = iter8v1alpha1.Iter8ReasonOverrideFailed
+		condition.Message = strings.Join(failures, "; ")
+	}
+	r.setCondition(iter8, condition)
+
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to apply %d override(s): %s", len(failures), condition.Message)
+	}
+	return nil
+}
+
+// overrideResultsForIter8 returns the outcome of each override of an Iter8 resource, by index: nil if applied,
+// the error if not. The overrides are applied afresh to the generated objects, so that results are not kept
+// between reconciles. Overrides that match no generated object have no entry.
+func (r *Iter8Reconciler) overrideResultsForIter8(iter8 *iter8v1alpha1.Iter8) map[int]error {
+	original := iter8.DeepCopy()
+	original.Spec.Overrides = nil
+
+	results := map[int]error{}
+	for _, obj := range r.generatedObjects(original) {
+		for i, err := range r.applyOverrides(iter8.Spec.Overrides, obj) {
+			// an override that failed for any object remains failed
+			if previous, ok := results[i]; ok && previous != nil {
+				continue
+			}
+			results[i] = err
+		}
+	}
+	return results
+}
+
+// generatedObjects returns the objects generated for an Iter8 resource to which overrides may apply
+func (r *Iter8Reconciler) generatedObjects(iter8 *iter8v1alpha1.Iter8) []runtime.Object {
+	metrics, _ := r.metricsForIter8(iter8)
+	controllerService := r.serviceForIter8Controller(iter8)
+	analyticsService := r.serviceForAnalytics(iter8)
+	objects := []runtime.Object{
+		r.serviceAccountForIter8Controller(iter8),
+		r.notifierConfigMapForIter8(iter8),
+		r.metricsConfigMapForIter8(iter8, metrics),
+		controllerService,
+		r.deploymentForIter8Controller(iter8),
+		r.configConfigMapForAnalytics(iter8),
+		analyticsService,
+		r.deploymentForIter8Analytics(iter8),
+	}
+	if nil != iter8.Spec.AnalyticsEngine.Autoscaling {
+		objects = append(objects, r.autoscalerForAnalytics(iter8))
+	}
+	if controllerReplicas(iter8) > 1 {
+		objects = append(objects, r.podDisruptionBudgetForIter8(iter8, controllerDefaultName))
+	}
+	if analyticsReplicas(iter8) > 1 {
+		objects = append(objects, r.podDisruptionBudgetForIter8(iter8, analyticsDefaultName))
+	}
+	objects = append(objects, r.exposureObjects(iter8, controllerService, iter8.Spec.Controller.Service)...)
+	return append(objects, r.exposureObjects(iter8, analyticsService, iter8.Spec.AnalyticsEngine.Service)...)
+}
+
+// exposureObjects returns the Ingress and OpenShift Route generated for a service, as specified
+func (r *Iter8Reconciler) exposureObjects(iter8 *iter8v1alpha1.Iter8, svc *corev1.Service, spec *iter8v1alpha1.ServiceSpec) []runtime.Object {
+	objects := []runtime.Object{}
+	if nil == spec {
+		return objects
+	}
+	if nil != spec.Ingress {
+		objects = append(objects, r.ingressForService(iter8, svc, spec.Ingress))
+	}
+	if nil != spec.Route && r.routes {
+		objects = append(objects, r.routeForService(iter8, svc, spec.Route))
+	}
+	return objects
+}
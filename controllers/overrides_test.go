@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+)
+
+// deploymentForTest returns a Deployment with one container with one environment variable
+func deploymentForTest() *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "iter8-controller", Namespace: "iter8"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:  "iter8-controller",
+						Image: "iter8/iter8-controller:v1",
+						Env:   []corev1.EnvVar{{Name: "A", Value: "1"}},
+					}},
+				},
+			},
+		},
+	}
+}
+
+func TestPatchObject(t *testing.T) {
+	strategic := iter8v1alpha1.PatchTypeStrategic
+	merge := iter8v1alpha1.PatchTypeMerge
+	json := iter8v1alpha1.PatchTypeJSON
+
+	tests := []struct {
+		name      string
+		patchType *string
+		patch     string
+		wantErr   bool
+		wantEnv   []string
+		wantImage string
+	}{{
+		name: "strategic by default merges lists by key",
+		patch: `spec:
+  template:
+    spec:
+      containers:
+      - name: iter8-controller
+        env:
+        - name: B
+          value: "2"`,
+		wantEnv:   []string{"B", "A"},
+		wantImage: "iter8/iter8-controller:v1",
+	}, {
+		name:      "strategic",
+		patchType: &strategic,
+		patch:     `{"spec": {"template": {"spec": {"containers": [{"name": "iter8-controller", "image": "iter8/iter8-controller:v2"}]}}}}`,
+		wantEnv:   []string{"A"},
+		wantImage: "iter8/iter8-controller:v2",
+	}, {
+		name:      "merge replaces lists",
+		patchType: &merge,
+		patch: `spec:
+  template:
+    spec:
+      containers:
+      - name: iter8-controller
+        image: iter8/iter8-controller:v2`,
+		wantEnv:   []string{},
+		wantImage: "iter8/iter8-controller:v2",
+	}, {
+		name:      "json",
+		patchType: &json,
+		patch: `- op: add
+  path: /spec/template/spec/containers/0/env/-
+  value:
+    name: B
+    value: "2"
+- op: replace
+  path: /spec/template/spec/containers/0/image
+  value: iter8/iter8-controller:v2`,
+		wantEnv:   []string{"A", "B"},
+		wantImage: "iter8/iter8-controller:v2",
+	}, {
+		name:      "json with missing path",
+		patchType: &json,
+		patch:     `[{"op": "replace", "path": "/spec/template/spec/containers/1/image", "value": "busybox"}]`,
+		wantErr:   true,
+	}, {
+		name:    "invalid YAML",
+		patch:   "spec: [",
+		wantErr: true,
+	}, {
+		name:      "does not decode",
+		patchType: &merge,
+		patch:     `{"spec": {"replicas": "two"}}`,
+		wantErr:   true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deploy := deploymentForTest()
+			err := patchObject(deploy, iter8v1alpha1.OverrideSpec{Kind: "Deployment", Name: deploy.Name, Type: tt.patchType, Patch: tt.patch})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("patchObject() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !equality.Semantic.DeepEqual(deploy, deploymentForTest()) {
+					t.Errorf("patchObject() changed the object on error")
+				}
+				return
+			}
+
+			container := deploy.Spec.Template.Spec.Containers[0]
+			if container.Image != tt.wantImage {
+				t.Errorf("image = %q, want %q", container.Image, tt.wantImage)
+			}
+			env := []string{}
+			for _, e := range container.Env {
+				env = append(env, e.Name)
+			}
+			if !reflect.DeepEqual(env, tt.wantEnv) {
+				t.Errorf("env = %v, want %v", env, tt.wantEnv)
+			}
+		})
+	}
+}
+
+func TestApplyOverrides(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	r := &Iter8Reconciler{Scheme: scheme}
+
+	replicas := `{"spec": {"replicas": 2}}`
+	tests := []struct {
+		name      string
+		overrides []iter8v1alpha1.OverrideSpec
+		want      map[int]bool
+	}{{
+		name: "no overrides",
+		want: map[int]bool{},
+	}, {
+		name: "only matching overrides applied",
+		overrides: []iter8v1alpha1.OverrideSpec{
+			{Kind: "Deployment", Name: "iter8-analytics", Patch: replicas},
+			{Kind: "Service", Name: "iter8-controller", Patch: replicas},
+			{Kind: "Deployment", Name: "iter8-controller", Patch: replicas},
+		},
+		want: map[int]bool{2: true},
+	}, {
+		name: "failed override reported",
+		overrides: []iter8v1alpha1.OverrideSpec{
+			{Kind: "Deployment", Name: "iter8-controller", Patch: "spec: ["},
+			{Kind: "Deployment", Name: "iter8-controller", Patch: replicas},
+		},
+		want: map[int]bool{0: false, 1: true},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := r.applyOverrides(tt.overrides, deploymentForTest())
+			if len(results) != len(tt.want) {
+				t.Fatalf("applyOverrides() = %v, want %d results", results, len(tt.want))
+			}
+			for i, applied := range tt.want {
+				err, ok := results[i]
+				if !ok {
+					t.Errorf("no result for override %d", i)
+				} else if applied != (err == nil) {
+					t.Errorf("override %d error = %v, want applied %v", i, err, applied)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"context"
+
+	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Cluster-scoped resources can't have an owner reference to the namespaced Iter8 resource.
+// Instead they are labeled with the namespace and name of the Iter8 resource that created them.
+const (
+	ownerNamespaceLabel = "iter8.tools/owner-namespace"
+	ownerNameLabel      = "iter8.tools/owner-name"
+)
+
+// ownerLabels returns the labels identifying iter8 as the owner of a cluster-scoped resource
+func ownerLabels(iter8 *iter8v1alpha1.Iter8) map[string]string {
+	return map[string]string{
+		ownerNamespaceLabel: iter8.Namespace,
+		ownerNameLabel:      iter8.Name,
+	}
+}
+
+// setOwnerLabels labels a cluster-scoped resource as owned by iter8
+func setOwnerLabels(iter8 *iter8v1alpha1.Iter8, obj metav1.Object) {
+	labels := obj.GetLabels()
+	if nil == labels {
+		labels = map[string]string{}
+	}
+	for k, v := range ownerLabels(iter8) {
+		labels[k] = v
+	}
+	obj.SetLabels(labels)
+}
+
+// isOwnedBy determines whether a cluster-scoped resource was created for iter8
+func isOwnedBy(iter8 *iter8v1alpha1.Iter8, obj metav1.Object) bool {
+	labels := obj.GetLabels()
+	return labels[ownerNamespaceLabel] == iter8.Namespace && labels[ownerNameLabel] == iter8.Name
+}
+
+// Cluster-scoped resources shared by all Iter8 instances, such as the experiments CustomResourceDefinition,
+// are not labeled with an owner; any Iter8 instance may update them and the last one removed deletes them.
+const sharedLabel = "iter8.tools/shared"
+
+// sharedLabels returns the labels of a cluster-scoped resource shared by all Iter8 instances. The common labels
+// of an Iter8 instance are not included so that all instances agree on them.
+func sharedLabels() map[string]string {
+	return map[string]string{
+		partOfLabel:    partOfValue,
+		managedByLabel: managedByValue,
+		sharedLabel:    "true",
+	}
+}
+
+// setSharedLabels labels a cluster-scoped resource as shared by all Iter8 instances, removing any owner labels
+func setSharedLabels(obj metav1.Object) {
+	labels := merge(obj.GetLabels(), sharedLabels())
+	delete(labels, ownerNamespaceLabel)
+	delete(labels, ownerNameLabel)
+	obj.SetLabels(labels)
+}
+
+// isShared determines whether a resource is labeled as shared by all Iter8 instances
+func isShared(obj metav1.Object) bool {
+	return obj.GetLabels()[sharedLabel] == "true"
+}
+
+// isManagedShared determines whether a shared resource is managed by the operator. Shared resources labeled
+// with an owner by earlier versions of the operator are also managed; they are labeled as shared when next
+// updated, so that they no longer depend on that owner.
+func isManagedShared(obj metav1.Object) bool {
+	return isShared(obj) || obj.GetLabels()[ownerNameLabel] != ""
+}
+
+// iter8ForOwnerLabels maps a change to a cluster-scoped resource to a reconcile request for the Iter8 resource
+// identified by its owner labels, if any
+func iter8ForOwnerLabels(obj handler.MapObject) []reconcile.Request {
+	labels := obj.Meta.GetLabels()
+	namespace, ok := labels[ownerNamespaceLabel]
+	if !ok {
+		return nil
+	}
+	name, ok := labels[ownerNameLabel]
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{{
+		NamespacedName: types.NamespacedName{Name: name, Namespace: namespace},
+	}}
+}
+
+// iter8sForClusterResource maps a change to a cluster-scoped resource to reconcile requests for the Iter8
+// resource that owns it or, if shared, for every Iter8 resource
+func (r *Iter8Reconciler) iter8sForClusterResource(obj handler.MapObject) []reconcile.Request {
+	if !isShared(obj.Meta) {
+		return iter8ForOwnerLabels(obj)
+	}
+	list := &iter8v1alpha1.Iter8List{}
+	err := r.Client.List(context.TODO(), list)
+	if err != nil {
+		r.Log.Error(err, "Unable to list Iter8 resources")
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, iter8 := range list.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: iter8.Name, Namespace: iter8.Namespace},
+		})
+	}
+	return requests
+}
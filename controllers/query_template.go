@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// Placeholders iter8 substitutes into metric query templates at experiment time
+const (
+	placeholderInterval      = "$interval"
+	placeholderOffset        = "$offset_str"
+	placeholderVersionLabels = "$version_labels"
+)
+
+// versionLabels are the labels substituted for $version_labels when validating a query template
+var versionLabels = []string{"destination_workload", "destination_workload_namespace"}
+
+// queryTemplateReplacer substitutes representative values for the placeholders so that the query can be parsed
+var queryTemplateReplacer = strings.NewReplacer(
+	placeholderInterval, "1m",
+	placeholderOffset, " offset 1m",
+	placeholderVersionLabels, strings.Join(versionLabels, ", "),
+)
+
+var unknownPlaceholder = regexp.MustCompile(`\$[A-Za-z_][A-Za-z0-9_]*`)
+
+// parseQueryTemplate substitutes the known placeholders in a query template and parses the result as PromQL
+func parseQueryTemplate(template string) (parser.Expr, error) {
+	query := queryTemplateReplacer.Replace(template)
+	if p := unknownPlaceholder.FindString(query); p != "" {
+		return nil, fmt.Errorf("unknown placeholder %s", p)
+	}
+	expr, err := parser.ParseExpr(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %s", err)
+	}
+	return expr, nil
+}
+
+// validateQueryTemplate checks that a query template is valid PromQL once the placeholders
+// have been substituted, and that its result is aggregated by ($version_labels)
+func validateQueryTemplate(template string) error {
+	expr, err := parseQueryTemplate(template)
+	if err != nil {
+		return err
+	}
+	if !aggregatesBy(expr, versionLabels) {
+		return fmt.Errorf("query must aggregate by (%s)", placeholderVersionLabels)
+	}
+	return nil
+}
+
+// aggregatesBy determines whether the result of an expression is aggregated by labels. Operations with a
+// scalar, and functions of vectors, preserve the aggregation of their vector operands; operations between
+// vectors require both to be aggregated and to be matched on labels.
+func aggregatesBy(expr parser.Expr, labels []string) bool {
+	switch e := expr.(type) {
+	case *parser.ParenExpr:
+		return aggregatesBy(e.Expr, labels)
+	case *parser.UnaryExpr:
+		return aggregatesBy(e.Expr, labels)
+	case *parser.AggregateExpr:
+		return !e.Without && containsAll(e.Grouping, labels)
+	case *parser.BinaryExpr:
+		switch {
+		case e.LHS.Type() == parser.ValueTypeScalar:
+			return aggregatesBy(e.RHS, labels)
+		case e.RHS.Type() == parser.ValueTypeScalar:
+			return aggregatesBy(e.LHS, labels)
+		}
+		if m := e.VectorMatching; nil != m {
+			if m.On && !containsAll(m.MatchingLabels, labels) {
+				return false
+			}
+			if !m.On && containsAny(m.MatchingLabels, labels) {
+				return false
+			}
+		}
+		return aggregatesBy(e.LHS, labels) && aggregatesBy(e.RHS, labels)
+	case *parser.Call:
+		vectors := 0
+		for _, arg := range e.Args {
+			if arg.Type() != parser.ValueTypeVector {
+				continue
+			}
+			if !aggregatesBy(arg, labels) {
+				return false
+			}
+			vectors++
+		}
+		return vectors > 0
+	default:
+		return false
+	}
+}
+
+// utility function determines if every str in strs is in an array
+func containsAll(arr []string, strs []string) bool {
+	for _, str := range strs {
+		if !contains(arr, str) {
+			return false
+		}
+	}
+	return true
+}
+
+// utility function determines if any str in strs is in an array
+func containsAny(arr []string, strs []string) bool {
+	for _, str := range strs {
+		if contains(arr, str) {
+			return true
+		}
+	}
+	return false
+}
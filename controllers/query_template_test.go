@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"testing"
+)
+
+func TestValidateQueryTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		valid    bool
+	}{{
+		name:     "sum by version labels",
+		template: "sum(increase(istio_requests_total{reporter='source'}[$interval]$offset_str)) by ($version_labels)",
+		valid:    true,
+	}, {
+		name:     "parenthesized aggregation",
+		template: "(sum(increase(istio_requests_total[$interval])) by ($version_labels))",
+		valid:    true,
+	}, {
+		name:     "ratio of aggregations",
+		template: "sum(increase(istio_requests_total{response_code=~'5..'}[$interval])) by ($version_labels) / sum(increase(istio_requests_total[$interval])) by ($version_labels)",
+		valid:    true,
+	}, {
+		name:     "aggregation scaled by a number",
+		template: "1000 * sum(increase(istio_request_duration_seconds_sum[$interval])) by ($version_labels)",
+		valid:    true,
+	}, {
+		name:     "function of an aggregation",
+		template: "histogram_quantile(0.95, sum(rate(istio_request_duration_milliseconds_bucket[$interval])) by (le, $version_labels))",
+		valid:    true,
+	}, {
+		name:     "ratio with an operand not aggregated",
+		template: "sum(increase(istio_requests_total[$interval])) by ($version_labels) / increase(istio_requests_total[$interval])",
+	}, {
+		name:     "ratio matched ignoring version labels",
+		template: "sum(increase(a[$interval])) by ($version_labels) / ignoring(destination_workload) sum(increase(b[$interval])) by ($version_labels)",
+	}, {
+		name:     "ratio matched on other labels",
+		template: "sum(increase(a[$interval])) by ($version_labels) / on(destination_service) sum(increase(b[$interval])) by ($version_labels)",
+	}, {
+		name:     "aggregation inside a function of a vector not aggregated",
+		template: "clamp_max(increase(istio_requests_total[$interval]), 1)",
+	}, {
+		name:     "not aggregated",
+		template: "increase(istio_requests_total[$interval])",
+	}, {
+		name:     "aggregated by other labels",
+		template: "sum(increase(istio_requests_total[$interval])) by (destination_service)",
+	}, {
+		name:     "aggregated without version labels",
+		template: "sum(increase(istio_requests_total[$interval])) without ($version_labels)",
+	}, {
+		name:     "unknown placeholder",
+		template: "sum(increase(istio_requests_total[$duration])) by ($version_labels)",
+	}, {
+		name:     "invalid PromQL",
+		template: "sum(increase(istio_requests_total[$interval]) by ($version_labels)",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateQueryTemplate(tt.template)
+			if tt.valid && err != nil {
+				t.Errorf("validateQueryTemplate() = %v, want nil", err)
+			}
+			if !tt.valid && err == nil {
+				t.Errorf("validateQueryTemplate() = nil, want error")
+			}
+		})
+	}
+}
+
+func TestContainsAny(t *testing.T) {
+	tests := []struct {
+		name string
+		arr  []string
+		strs []string
+		want bool
+	}{
+		{name: "one present", arr: []string{"a", "b"}, strs: []string{"c", "a"}, want: true},
+		{name: "none present", arr: []string{"a", "b"}, strs: []string{"c"}, want: false},
+		{name: "none required", arr: []string{"a"}, strs: []string{}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := containsAny(tt.arr, tt.strs); got != tt.want {
+				t.Errorf("containsAny() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContainsAll(t *testing.T) {
+	tests := []struct {
+		name string
+		arr  []string
+		strs []string
+		want bool
+	}{
+		{name: "all present", arr: []string{"a", "b", "c"}, strs: []string{"c", "a"}, want: true},
+		{name: "none required", arr: []string{"a"}, strs: []string{}, want: true},
+		{name: "one missing", arr: []string{"a", "b"}, strs: []string{"a", "d"}, want: false},
+		{name: "empty array", arr: nil, strs: []string{"a"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := containsAll(tt.arr, tt.strs); got != tt.want {
+				t.Errorf("containsAll() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
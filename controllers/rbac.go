@@ -2,64 +2,161 @@ package controllers
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
 
+// Default names of the cluster-scoped RBAC resources. Installations not created by the operator use
+// these names as is; the operator qualifies them with the namespace of the Iter8 instance.
 const (
+	// roleManifest defines the ClusterRole granted to the iter8 controller
+	roleManifest = "config/iter8/role.yaml"
+
 	roleDefaultName = "iter8-controller-role"
 
 	roleBindingDefaultName = "iter8-controller-rolebinding"
 )
 
 func (r *Iter8Reconciler) rbacForIter8(iter8 *iter8v1alpha1.Iter8) error {
+	err := r.experimentRolesForIter8(iter8)
+	if err != nil {
+		return err
+	}
+
+	var drift []string
+	if iter8v1alpha1.GetScope(iter8.Spec) == iter8v1alpha1.ScopeNamespaces {
+		drift, err = r.namespacedRBACForIter8(iter8)
+	} else {
+		drift, err = r.clusterRBACForIter8(iter8)
+	}
+	if err == nil {
+		err = r.deleteLegacyRBACForIter8(iter8)
+	}
+	r.rbacDriftForIter8(iter8, drift, err)
+	return err
+}
 
-	err := r.roleForIter8(iter8)
+// deleteLegacyRBACForIter8 deletes the ClusterRoleBinding and ClusterRole with the default names once they have
+// been adopted; the RBAC resources named for the Iter8 instance replace them
+func (r *Iter8Reconciler) deleteLegacyRBACForIter8(iter8 *iter8v1alpha1.Iter8) error {
+	err := r.deleteIfOwned(iter8, &rbacv1.ClusterRoleBinding{}, roleBindingDefaultName)
 	if err != nil {
-		ctrl.Log.Error(err, "Failed to create ClusterRole")
+		ctrl.Log.Error(err, "Failed to delete adopted ClusterRoleBinding", "name", roleBindingDefaultName)
 		return err
 	}
-	err = r.createOrUpdateClusterRoleBindingForIter8(iter8)
+	err = r.deleteIfOwned(iter8, &rbacv1.ClusterRole{}, roleDefaultName)
 	if err != nil {
-		ctrl.Log.Error(err, "Failed to create ClusterRoleBinding")
+		ctrl.Log.Error(err, "Failed to delete adopted ClusterRole", "name", roleDefaultName)
 	}
 	return err
 }
 
-func (r *Iter8Reconciler) roleForIter8(iter8 *iter8v1alpha1.Iter8) error {
-	found := &rbacv1.ClusterRole{}
-	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: roleDefaultName}, found)
+// clusterRBACForIter8 grants the iter8 controller a ClusterRole. The Roles in onboarded namespaces are kept
+// as well, so that onboarding a namespace grants the same permissions regardless of scope.
+func (r *Iter8Reconciler) clusterRBACForIter8(iter8 *iter8v1alpha1.Iter8) ([]string, error) {
+	drift, err := r.rolesForIter8(iter8)
+	if err != nil {
+		return drift, err
+	}
+
+	drifted, err := r.createOrUpdateClusterRoleForIter8(iter8)
+	if err != nil {
+		ctrl.Log.Error(err, "Failed to create ClusterRole")
+		return drift, err
+	}
+	if drifted {
+		drift = append(drift, "ClusterRole "+roleName(iter8))
+	}
+	drifted, err = r.createOrUpdateClusterRoleBindingForIter8(iter8)
+	if err != nil {
+		ctrl.Log.Error(err, "Failed to create ClusterRoleBinding")
+		return drift, err
+	}
+	if drifted {
+		drift = append(drift, "ClusterRoleBinding "+roleBindingName(iter8))
+	}
+	return drift, nil
+}
+
+// rbacDriftForIter8 records in status whether the RBAC resources granted to the iter8 controller matched the
+// desired state. Corrected drift is also recorded as an Event.
+func (r *Iter8Reconciler) rbacDriftForIter8(iter8 *iter8v1alpha1.Iter8, drift []string, err error) {
+	condition := iter8v1alpha1.Condition{
+		Type:   iter8v1alpha1.Iter8ConditionRBACInSync,
+		Status: corev1.ConditionTrue,
+		Reason: iter8v1alpha1.Iter8ReasonRBACInSync,
+	}
+	switch {
+	case err != nil:
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = iter8v1alpha1.Iter8ReasonRBACUpdateFailed
+		condition.Message = err.Error()
+	case len(drift) > 0:
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = iter8v1alpha1.Iter8ReasonRBACDriftCorrected
+		condition.Message = "Updated " + strings.Join(drift, ", ")
+		if nil != r.Recorder {
+			r.Recorder.Event(iter8, corev1.EventTypeNormal, condition.Reason, condition.Message)
+		}
+	}
+	r.setCondition(iter8, condition)
+}
+
+// createOrUpdateClusterRoleForIter8 creates the ClusterRole for the iter8 controller or updates it to
+// match config/iter8/role.yaml. Returns true if an existing ClusterRole was updated.
+func (r *Iter8Reconciler) createOrUpdateClusterRoleForIter8(iter8 *iter8v1alpha1.Iter8) (bool, error) {
+	// Desired state
+	role, err := r.clusterRoleForIter8(iter8)
+	if err != nil {
+		return false, err
+	}
+
+	result := r.apply(iter8, role)
+	return result.Operation == applyUpdated, result.Err
+}
+
+// clusterRoleForIter8 returns the ClusterRole defined in config/iter8/role.yaml, named for the Iter8 instance
+func (r *Iter8Reconciler) clusterRoleForIter8(iter8 *iter8v1alpha1.Iter8) (*rbacv1.ClusterRole, error) {
+	objects, err := readManifests(roleManifest, r.Scheme)
 	if err != nil {
-		// not present
-		err = r.fromYaml("config/iter8/role.yaml", iter8)
-		if err != nil {
-			return err
+		r.Log.Error(err, "Error reading manifest", "file", roleManifest)
+		return nil, err
+	}
+	for _, obj := range objects {
+		if role, ok := obj.(*rbacv1.ClusterRole); ok {
+			role.Name = roleName(iter8)
+			setOwnerLabels(iter8, role)
+			setMetadata(iter8, role, labelsForInstance(iter8))
+			return role, nil
 		}
-	} else {
-		// // make sure has owner
-		// owner := found.GetOwnerReferences()
-		// if 0 == len(owner) {
-		// 	ctrl.Log.Info("Updating owner on ClusterRole", "name", found.Name)
-		// 	// Set Iter8 instance as the owner and controller
-		// 	ctrl.Log.Info("Updating owner on ClusterRole", "found original", found.GetOwnerReferences())
-		// 	err = controllerutil.SetControllerReference(iter8, found, r.scheme)
-		// 	if err != nil {
-		// 		ctrl.Log.Error(err, "Can't set owner")
-		// 	}
-		// 	ctrl.Log.Info("Updating owner on ClusterRole", "found modified", found.GetOwnerReferences())
-		// 	err = r.client.Update(context.TODO(), found)
-		// 	return err
-		// }
-		ctrl.Log.Info("ClusterRole already present", "name", found.Name)
-	}
-	return nil
+	}
+	return nil, fmt.Errorf("No ClusterRole found in %s", roleManifest)
+}
+
+// roleName returns the name of the ClusterRole or Roles for the Iter8 instance. Names include the
+// namespace of the Iter8 instance so that Iter8 instances in different namespaces do not collide.
+func roleName(iter8 *iter8v1alpha1.Iter8) string {
+	return roleDefaultName + "-" + iter8.Namespace
+}
+
+// roleBindingName returns the name of the ClusterRoleBinding or RoleBindings for the Iter8 instance
+func roleBindingName(iter8 *iter8v1alpha1.Iter8) string {
+	return roleBindingDefaultName + "-" + iter8.Namespace
 }
 
-func (r *Iter8Reconciler) createOrUpdateClusterRoleBindingForIter8(iter8 *iter8v1alpha1.Iter8) error {
+// createOrUpdateClusterRoleBindingForIter8 creates the ClusterRoleBinding for the iter8 controller or updates
+// its subjects. Since the role of a binding can't be changed, a binding to the wrong role is recreated. A binding
+// not labeled as owned by iter8 is left unchanged. Returns true if an existing binding was changed.
+func (r *Iter8Reconciler) createOrUpdateClusterRoleBindingForIter8(iter8 *iter8v1alpha1.Iter8) (bool, error) {
 	// Desired state
 	rolebinding := r.clusterRoleBindingForIter8(iter8)
 
@@ -67,22 +164,39 @@ func (r *Iter8Reconciler) createOrUpdateClusterRoleBindingForIter8(iter8 *iter8v
 	found := &rbacv1.ClusterRoleBinding{}
 	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: rolebinding.Name}, found)
 	if err != nil {
-		return r.Client.Create(context.TODO(), rolebinding)
+		if errors.IsNotFound(err) {
+			return false, r.Client.Create(context.TODO(), rolebinding)
+		}
+		return false, err
+	}
+	if !isOwnedBy(iter8, found) {
+		ctrl.Log.Info("ClusterRoleBinding already present and not managed by Iter8 resource", "name", found.Name)
+		return false, nil
 	}
 
 	// If changed, update
-	ctrl.Log.Info("ClusterRoleBinding already present", "name", rolebinding.Name)
-	// service.ResourceVersion = found.GetResourceVersion()
-	// service.Spec = corev1.ServiceSpec{}
-	// This causes errors; not sure why
-	// return r.client.Update(context.TODO(), service)
-	return nil
+	if found.RoleRef != rolebinding.RoleRef {
+		ctrl.Log.Info("Recreating ClusterRoleBinding with changed role", "name", rolebinding.Name)
+		err = r.Client.Delete(context.TODO(), found)
+		if err != nil && !errors.IsNotFound(err) {
+			return false, err
+		}
+		return true, r.Client.Create(context.TODO(), rolebinding)
+	}
+	if equality.Semantic.DeepEqual(found.Subjects, rolebinding.Subjects) {
+		return false, r.updateMetadata(found, rolebinding)
+	}
+	ctrl.Log.Info("Updating ClusterRoleBinding subjects", "name", rolebinding.Name)
+	found.Subjects = rolebinding.Subjects
+	mergeMetadata(found, rolebinding)
+	return true, r.Client.Update(context.TODO(), found)
 }
 
 func (r *Iter8Reconciler) clusterRoleBindingForIter8(iter8 *iter8v1alpha1.Iter8) *rbacv1.ClusterRoleBinding {
 	rolebinding := &rbacv1.ClusterRoleBinding{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: roleBindingDefaultName,
+			Name:   roleBindingName(iter8),
+			Labels: ownerLabels(iter8),
 		},
 		Subjects: []rbacv1.Subject{{
 			Kind:      "ServiceAccount",
@@ -91,13 +205,14 @@ func (r *Iter8Reconciler) clusterRoleBindingForIter8(iter8 *iter8v1alpha1.Iter8)
 		}},
 		RoleRef: rbacv1.RoleRef{
 			Kind:     "ClusterRole",
-			Name:     roleDefaultName,
+			Name:     roleName(iter8),
 			APIGroup: "rbac.authorization.k8s.io",
 		},
 	}
+	setMetadata(iter8, rolebinding, labelsForInstance(iter8))
 
 	// This doesn't work for cluster-scoped objects; they can't be owned by a namespace-scoped thing
-	// Finalizers will be used to delete this instead
+	// Owner labels identify the Iter8 instance instead and finalizers will be used to delete this
 	// // Set Iter8 instance as the owner and controller
 	// controllerutil.SetControllerReference(iter8, sa, r.scheme)
 	return rolebinding
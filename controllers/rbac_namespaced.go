@@ -0,0 +1,310 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// watchNamespaceEnv is the environment variable listing the namespaces watched by the iter8 controller
+const watchNamespaceEnv = "WATCH_NAMESPACE"
+
+// clusterScopedResources lists, by API group, the cluster-scoped resources in the rules granted to the iter8
+// controller. A Role can't grant access to them, so they are omitted from the rules of Roles.
+var clusterScopedResources = map[string][]string{
+	"admissionregistration.k8s.io": {"mutatingwebhookconfigurations", "validatingwebhookconfigurations"},
+}
+
+// namespacedRBACForIter8 grants the iter8 controller a Role in its own namespace, where it reads its
+// configuration and elects a leader, and in each onboarded namespace, instead of a ClusterRole.
+// Roles and RoleBindings in namespaces no longer watched are deleted, as are the ClusterRole and
+// ClusterRoleBinding if they were created for cluster scope.
+func (r *Iter8Reconciler) namespacedRBACForIter8(iter8 *iter8v1alpha1.Iter8) ([]string, error) {
+	drift, err := r.rolesForIter8(iter8)
+	if err != nil {
+		return drift, err
+	}
+
+	err = r.deleteIfOwned(iter8, &rbacv1.ClusterRoleBinding{}, roleBindingName(iter8))
+	if err != nil {
+		return drift, err
+	}
+	return drift, r.deleteIfOwned(iter8, &rbacv1.ClusterRole{}, roleName(iter8))
+}
+
+// rolesForIter8 grants the iter8 controller a Role in each of rbacNamespaces and deletes the Roles and
+// RoleBindings in other namespaces. Returns the Roles and RoleBindings whose drift was corrected.
+func (r *Iter8Reconciler) rolesForIter8(iter8 *iter8v1alpha1.Iter8) ([]string, error) {
+	clusterRole, err := r.clusterRoleForIter8(iter8)
+	if err != nil {
+		return nil, err
+	}
+	rules := namespacedRules(clusterRole.Rules)
+	namespaces := rbacNamespaces(iter8)
+
+	drift := []string{}
+	for _, namespace := range namespaces {
+		drifted, err := r.createOrUpdateRoleForIter8(r.roleForNamespace(iter8, namespace, rules))
+		if err != nil {
+			ctrl.Log.Error(err, "Failed to create Role", "namespace", namespace)
+			return drift, err
+		}
+		if drifted {
+			drift = append(drift, "Role "+namespace+"/"+roleName(iter8))
+		}
+		drifted, err = r.createOrUpdateRoleBindingForIter8(iter8, r.roleBindingForNamespace(iter8, namespace))
+		if err != nil {
+			ctrl.Log.Error(err, "Failed to create RoleBinding", "namespace", namespace)
+			return drift, err
+		}
+		if drifted {
+			drift = append(drift, "RoleBinding "+namespace+"/"+roleBindingName(iter8))
+		}
+	}
+
+	err = r.pruneNamespacedRBACForIter8(iter8, namespaces)
+	if err != nil {
+		ctrl.Log.Error(err, "Failed to delete Roles and RoleBindings")
+	}
+	return drift, err
+}
+
+// rbacNamespaces returns the namespaces in which the iter8 controller is granted a Role: the namespace of the
+// Iter8 resource and the onboarded namespaces
+func rbacNamespaces(iter8 *iter8v1alpha1.Iter8) []string {
+	namespaces := []string{iter8.Namespace}
+	for _, namespace := range iter8.Status.OnboardedNamespaces {
+		if !contains(namespaces, namespace) {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
+
+// namespacedRules returns rules without the resources in clusterScopedResources. Rules left without
+// resources are omitted, as are rules for non-resource URLs, which a Role cannot grant.
+func namespacedRules(rules []rbacv1.PolicyRule) []rbacv1.PolicyRule {
+	result := []rbacv1.PolicyRule{}
+	for _, rule := range rules {
+		if len(rule.NonResourceURLs) > 0 {
+			continue
+		}
+		resources := []string{}
+		for _, resource := range rule.Resources {
+			if !isClusterScopedResource(rule.APIGroups, resource) {
+				resources = append(resources, resource)
+			}
+		}
+		if len(resources) == 0 && len(rule.Resources) > 0 {
+			continue
+		}
+		rule.Resources = resources
+		result = append(result, rule)
+	}
+	return result
+}
+
+// isClusterScopedResource determines whether a resource in any of groups is listed in clusterScopedResources
+func isClusterScopedResource(groups []string, resource string) bool {
+	for _, group := range groups {
+		if contains(clusterScopedResources[group], resource) {
+			return true
+		}
+	}
+	return false
+}
+
+// createOrUpdateRoleForIter8 creates a Role or updates its rules. Returns true if existing rules were updated.
+func (r *Iter8Reconciler) createOrUpdateRoleForIter8(role *rbacv1.Role) (bool, error) {
+	found := &rbacv1.Role{}
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: role.Name, Namespace: role.Namespace}, found)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, r.Client.Create(context.TODO(), role)
+		}
+		return false, err
+	}
+
+	// If changed, update
+	if equality.Semantic.DeepEqual(found.Rules, role.Rules) {
+		return false, r.updateMetadata(found, role)
+	}
+	ctrl.Log.Info("Updating Role", "name", role.Name, "namespace", role.Namespace)
+	found.Rules = role.Rules
+	mergeMetadata(found, role)
+	return true, r.Client.Update(context.TODO(), found)
+}
+
+// createOrUpdateRoleBindingForIter8 creates a RoleBinding or updates its subjects. Since the role of a binding
+// can't be changed, a binding to the wrong role is recreated. A binding not labeled as owned by iter8 is left
+// unchanged. Returns true if an existing binding was changed.
+func (r *Iter8Reconciler) createOrUpdateRoleBindingForIter8(iter8 *iter8v1alpha1.Iter8, rolebinding *rbacv1.RoleBinding) (bool, error) {
+	found := &rbacv1.RoleBinding{}
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: rolebinding.Name, Namespace: rolebinding.Namespace}, found)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, r.Client.Create(context.TODO(), rolebinding)
+		}
+		return false, err
+	}
+	if !isOwnedBy(iter8, found) {
+		ctrl.Log.Info("RoleBinding already present and not managed by Iter8 resource", "name", found.Name, "namespace", found.Namespace)
+		return false, nil
+	}
+
+	// If changed, update
+	if found.RoleRef != rolebinding.RoleRef {
+		ctrl.Log.Info("Recreating RoleBinding with changed role", "name", rolebinding.Name, "namespace", rolebinding.Namespace)
+		err = r.Client.Delete(context.TODO(), found)
+		if err != nil && !errors.IsNotFound(err) {
+			return false, err
+		}
+		return true, r.Client.Create(context.TODO(), rolebinding)
+	}
+	if equality.Semantic.DeepEqual(found.Subjects, rolebinding.Subjects) {
+		return false, r.updateMetadata(found, rolebinding)
+	}
+	ctrl.Log.Info("Updating RoleBinding", "name", rolebinding.Name, "namespace", rolebinding.Namespace)
+	found.Subjects = rolebinding.Subjects
+	mergeMetadata(found, rolebinding)
+	return true, r.Client.Update(context.TODO(), found)
+}
+
+func (r *Iter8Reconciler) roleForNamespace(iter8 *iter8v1alpha1.Iter8, namespace string, rules []rbacv1.PolicyRule) *rbacv1.Role {
+	// A Role in another namespace can't be owned by the Iter8 instance; label it instead
+	role := &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      roleName(iter8),
+			Namespace: namespace,
+			Labels:    ownerLabels(iter8),
+		},
+		Rules: rules,
+	}
+	setMetadata(iter8, role, labelsForInstance(iter8))
+	return role
+}
+
+func (r *Iter8Reconciler) roleBindingForNamespace(iter8 *iter8v1alpha1.Iter8, namespace string) *rbacv1.RoleBinding {
+	rolebinding := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      roleBindingName(iter8),
+			Namespace: namespace,
+			Labels:    ownerLabels(iter8),
+		},
+		Subjects: []rbacv1.Subject{{
+			Kind:      "ServiceAccount",
+			Name:      controllerDefaultName,
+			Namespace: iter8.Namespace,
+		}},
+		RoleRef: rbacv1.RoleRef{
+			Kind:     "Role",
+			Name:     roleName(iter8),
+			APIGroup: "rbac.authorization.k8s.io",
+		},
+	}
+	setMetadata(iter8, rolebinding, labelsForInstance(iter8))
+	return rolebinding
+}
+
+// pruneNamespacedRBACForIter8 deletes the Roles and RoleBindings created for iter8 outside of namespaces
+func (r *Iter8Reconciler) pruneNamespacedRBACForIter8(iter8 *iter8v1alpha1.Iter8, namespaces []string) error {
+	rolebindings := &rbacv1.RoleBindingList{}
+	err := r.Client.List(context.TODO(), rolebindings, client.MatchingLabels(ownerLabels(iter8)))
+	if err != nil {
+		return err
+	}
+	for i := range rolebindings.Items {
+		err = r.pruneObject(&rolebindings.Items[i], rolebindings.Items[i].Namespace, namespaces)
+		if err != nil {
+			return err
+		}
+	}
+
+	roles := &rbacv1.RoleList{}
+	err = r.Client.List(context.TODO(), roles, client.MatchingLabels(ownerLabels(iter8)))
+	if err != nil {
+		return err
+	}
+	for i := range roles.Items {
+		err = r.pruneObject(&roles.Items[i], roles.Items[i].Namespace, namespaces)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneObject deletes an object unless it is in one of namespaces
+func (r *Iter8Reconciler) pruneObject(obj runtime.Object, namespace string, namespaces []string) error {
+	if contains(namespaces, namespace) {
+		return nil
+	}
+	ctrl.Log.Info("Deleting object in namespace no longer watched", "kind", obj.GetObjectKind().GroupVersionKind().Kind, "namespace", namespace)
+	err := r.Client.Delete(context.TODO(), obj)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// deleteIfOwned deletes the named cluster-scoped object if it was created for iter8
+func (r *Iter8Reconciler) deleteIfOwned(iter8 *iter8v1alpha1.Iter8, obj runtime.Object, name string) error {
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: name}, obj)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if !isOwnedBy(iter8, obj.(metav1.Object)) {
+		return nil
+	}
+	ctrl.Log.Info("Deleting cluster-scoped object no longer needed", "name", name)
+	err = r.Client.Delete(context.TODO(), obj)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// deleteIfShared deletes the named cluster-scoped object if it is shared by the Iter8 instances
+func (r *Iter8Reconciler) deleteIfShared(obj runtime.Object, name string) error {
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: name}, obj)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if !isManagedShared(obj.(metav1.Object)) {
+		return nil
+	}
+	ctrl.Log.Info("Deleting shared cluster-scoped object no longer needed", "name", name)
+	err = r.Client.Delete(context.TODO(), obj)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// watchNamespaces returns the value of the WATCH_NAMESPACE environment variable for the iter8 controller.
+// An empty value watches all namespaces. In the namespaces scope, the controller watches its own namespace
+// until namespaces are onboarded, since it is granted no access to the others.
+func watchNamespaces(iter8 *iter8v1alpha1.Iter8) string {
+	if iter8v1alpha1.GetScope(iter8.Spec) != iter8v1alpha1.ScopeNamespaces {
+		return ""
+	}
+	if len(iter8.Status.OnboardedNamespaces) == 0 {
+		return iter8.Namespace
+	}
+	return strings.Join(iter8.Status.OnboardedNamespaces, ",")
+}
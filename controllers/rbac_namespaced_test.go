@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestNamespacedRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []rbacv1.PolicyRule
+		want  []rbacv1.PolicyRule
+	}{{
+		name:  "namespaced rules kept",
+		rules: []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get"}}},
+		want:  []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get"}}},
+	}, {
+		name: "cluster-scoped resources removed",
+		rules: []rbacv1.PolicyRule{{
+			APIGroups: []string{"admissionregistration.k8s.io"},
+			Resources: []string{"mutatingwebhookconfigurations", "validatingwebhookconfigurations"},
+			Verbs:     []string{"get"},
+		}, {
+			APIGroups: []string{"apps"},
+			Resources: []string{"deployments"},
+			Verbs:     []string{"get"},
+		}},
+		want: []rbacv1.PolicyRule{{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"get"}}},
+	}, {
+		name: "rule keeps its namespaced resources",
+		rules: []rbacv1.PolicyRule{{
+			APIGroups: []string{"admissionregistration.k8s.io"},
+			Resources: []string{"mutatingwebhookconfigurations", "other"},
+			Verbs:     []string{"get"},
+		}},
+		want: []rbacv1.PolicyRule{{APIGroups: []string{"admissionregistration.k8s.io"}, Resources: []string{"other"}, Verbs: []string{"get"}}},
+	}, {
+		name: "resource of the same name in another group kept",
+		rules: []rbacv1.PolicyRule{{
+			APIGroups: []string{"example.com"},
+			Resources: []string{"mutatingwebhookconfigurations"},
+			Verbs:     []string{"get"},
+		}},
+		want: []rbacv1.PolicyRule{{APIGroups: []string{"example.com"}, Resources: []string{"mutatingwebhookconfigurations"}, Verbs: []string{"get"}}},
+	}, {
+		name: "non-resource URLs removed",
+		rules: []rbacv1.PolicyRule{
+			{NonResourceURLs: []string{"/metrics"}, Verbs: []string{"get"}},
+			{APIGroups: []string{""}, Resources: []string{"events"}, Verbs: []string{"create"}},
+		},
+		want: []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"events"}, Verbs: []string{"create"}}},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := namespacedRules(tt.rules); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("namespacedRules() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRBACNamespaces(t *testing.T) {
+	tests := []struct {
+		name      string
+		onboarded []string
+		want      []string
+	}{
+		{name: "none onboarded", want: []string{"iter8"}},
+		{name: "onboarded", onboarded: []string{"apps", "other"}, want: []string{"iter8", "apps", "other"}},
+		{name: "own namespace onboarded", onboarded: []string{"apps", "iter8"}, want: []string{"iter8", "apps"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			iter8 := iter8ForTest("iter8", "iter8", 0, tt.onboarded...)
+			if got := rbacNamespaces(&iter8); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rbacNamespaces() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatchNamespaces(t *testing.T) {
+	tests := []struct {
+		name      string
+		scope     string
+		onboarded []string
+		want      string
+	}{
+		{name: "cluster scope", scope: iter8v1alpha1.ScopeCluster, onboarded: []string{"apps"}, want: ""},
+		{name: "onboarded namespaces", scope: iter8v1alpha1.ScopeNamespaces, onboarded: []string{"apps", "other"}, want: "apps,other"},
+		{name: "none onboarded", scope: iter8v1alpha1.ScopeNamespaces, want: "iter8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			iter8 := iter8ForTest("iter8", "iter8", 0, tt.onboarded...)
+			iter8.Spec.Scope = &tt.scope
+			if got := watchNamespaces(&iter8); got != tt.want {
+				t.Errorf("watchNamespaces() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateOrUpdateRoleBindingForIter8(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	iter8 := iter8ForTest("iter8", "iter8", 0, "apps")
+
+	tests := []struct {
+		name         string
+		owned        bool
+		wantDrift    bool
+		wantRoleName string
+	}{
+		{name: "owned binding to another role recreated", owned: true, wantDrift: true, wantRoleName: roleName(&iter8)},
+		{name: "binding not owned left unchanged", owned: false, wantDrift: false, wantRoleName: "other"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Iter8Reconciler{Log: logf.Log.WithName("test"), Scheme: scheme}
+			found := r.roleBindingForNamespace(&iter8, "apps")
+			found.RoleRef.Name = "other"
+			if !tt.owned {
+				found.Labels = nil
+			}
+			r.Client = fake.NewFakeClientWithScheme(scheme, found)
+
+			drifted, err := r.createOrUpdateRoleBindingForIter8(&iter8, r.roleBindingForNamespace(&iter8, "apps"))
+			if err != nil {
+				t.Fatalf("createOrUpdateRoleBindingForIter8() error = %v", err)
+			}
+			if drifted != tt.wantDrift {
+				t.Errorf("createOrUpdateRoleBindingForIter8() = %v, want %v", drifted, tt.wantDrift)
+			}
+			got := &rbacv1.RoleBinding{}
+			err = r.Client.Get(context.TODO(), types.NamespacedName{Name: found.Name, Namespace: found.Namespace}, got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.RoleRef.Name != tt.wantRoleName {
+				t.Errorf("role = %q, want %q", got.RoleRef.Name, tt.wantRoleName)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// defaultRunAsUser is the user and group the components run as, except on OpenShift where they are
+	// assigned by the SecurityContextConstraints
+	defaultRunAsUser int64 = 65532
+
+	seccompPodAnnotation  = "seccomp.security.alpha.kubernetes.io/pod"
+	seccompRuntimeDefault = "runtime/default"
+
+	// tmpVolumeName is the writable volume mounted at /tmp, since the root filesystem is read-only
+	tmpVolumeName = "tmp"
+)
+
+// isOpenShift determines whether the cluster serves the OpenShift SecurityContextConstraints API
+func isOpenShift(mapper meta.RESTMapper) bool {
+	return servesKind(mapper, schema.GroupKind{Group: "security.openshift.io", Kind: "SecurityContextConstraints"})
+}
+
+// setSecurityContext sets the security contexts of a pod and of its first container, the component, replacing
+// the defaults with those specified in deploy. Containers added by users are left as specified.
+func setSecurityContext(template *corev1.PodTemplateSpec, deploy iter8v1alpha1.DeploymentSpec, openShift bool) {
+	pod := &template.Spec
+	if nil != deploy.PodSecurityContext {
+		pod.SecurityContext = deploy.PodSecurityContext
+	} else {
+		pod.SecurityContext = defaultPodSecurityContext(openShift)
+		// the seccomp profile of pods is set by annotation since the seccompProfile field was added in
+		// Kubernetes 1.19, after the API used here. Pod Security admission checks only the field, so the
+		// restricted level can't be enforced on these pods. OpenShift rejects the annotation unless allowed
+		// by the SecurityContextConstraints.
+		if !openShift {
+			if nil == template.Annotations {
+				template.Annotations = map[string]string{}
+			}
+			template.Annotations[seccompPodAnnotation] = seccompRuntimeDefault
+		}
+	}
+
+	setTmpVolume(pod)
+	container := &pod.Containers[0]
+	container.SecurityContext = defaultSecurityContext()
+	if nil != deploy.SecurityContext {
+		container.SecurityContext = deploy.SecurityContext
+	}
+	setTmpVolumeMount(container)
+}
+
+func setTmpVolume(pod *corev1.PodSpec) {
+	for _, volume := range pod.Volumes {
+		if volume.Name == tmpVolumeName {
+			return
+		}
+	}
+	pod.Volumes = append(pod.Volumes, corev1.Volume{
+		Name: tmpVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{},
+		},
+	})
+}
+
+func setTmpVolumeMount(container *corev1.Container) {
+	for _, mount := range container.VolumeMounts {
+		if mount.MountPath == "/tmp" {
+			return
+		}
+	}
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      tmpVolumeName,
+		MountPath: "/tmp",
+	})
+}
+
+func defaultPodSecurityContext(openShift bool) *corev1.PodSecurityContext {
+	runAsNonRoot := true
+	context := &corev1.PodSecurityContext{
+		RunAsNonRoot: &runAsNonRoot,
+	}
+	if !openShift {
+		user := defaultRunAsUser
+		context.RunAsUser = &user
+		context.RunAsGroup = &user
+		context.FSGroup = &user
+	}
+	return context
+}
+
+func defaultSecurityContext() *corev1.SecurityContext {
+	runAsNonRoot := true
+	allowPrivilegeEscalation := false
+	readOnlyRootFilesystem := true
+	return &corev1.SecurityContext{
+		RunAsNonRoot:             &runAsNonRoot,
+		AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+		ReadOnlyRootFilesystem:   &readOnlyRootFilesystem,
+		Capabilities: &corev1.Capabilities{
+			Drop: []corev1.Capability{"ALL"},
+		},
+	}
+}
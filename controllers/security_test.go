@@ -0,0 +1,25 @@
+package controllers
+
+import (
+	"testing"
+
+	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestSetSecurityContextTmpVolume(t *testing.T) {
+	template := &corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "manager"}}},
+	}
+
+	// applied again, for example to a template that already has the volume
+	setSecurityContext(template, iter8v1alpha1.DeploymentSpec{}, false)
+	setSecurityContext(template, iter8v1alpha1.DeploymentSpec{}, false)
+
+	if len(template.Spec.Volumes) != 1 || template.Spec.Volumes[0].Name != tmpVolumeName {
+		t.Errorf("volumes = %v, want one %s volume", template.Spec.Volumes, tmpVolumeName)
+	}
+	if mounts := template.Spec.Containers[0].VolumeMounts; len(mounts) != 1 || mounts[0].MountPath != "/tmp" {
+		t.Errorf("volume mounts = %v, want one at /tmp", mounts)
+	}
+}
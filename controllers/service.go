@@ -0,0 +1,231 @@
+package controllers
+
+import (
+	"context"
+
+	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// routeGVK identifies OpenShift Routes, which are handled as unstructured objects
+var routeGVK = schema.GroupVersionKind{Group: "route.openshift.io", Version: "v1", Kind: "Route"}
+
+// servesKind determines whether the cluster serves an API kind
+func servesKind(mapper meta.RESTMapper, kind schema.GroupKind) bool {
+	_, err := mapper.RESTMapping(kind)
+	return err == nil
+}
+
+// setServiceExposure sets the type, annotations and port of a service with a single port. By default,
+// the port is named portName and forwards to the container port of the same number.
+func setServiceExposure(svc *corev1.Service, spec *iter8v1alpha1.ServiceSpec, portName string) {
+	svc.Spec.Type = iter8v1alpha1.GetServiceType(spec)
+	port := &svc.Spec.Ports[0]
+	port.Name = portName
+	port.Protocol = corev1.ProtocolTCP
+	port.TargetPort = intstr.FromInt(int(port.Port))
+	if nil == spec {
+		return
+	}
+
+	if nil != spec.PortName {
+		port.Name = *spec.PortName
+	}
+	if nil != spec.TargetPort {
+		port.TargetPort = *spec.TargetPort
+	}
+	if nil != spec.NodePort && svc.Spec.Type != corev1.ServiceTypeClusterIP {
+		port.NodePort = *spec.NodePort
+	}
+	svc.Annotations = merge(svc.Annotations, spec.Annotations)
+}
+
+// createOrUpdateService creates a Service or updates its type, ports and selector. The cluster IP and
+// allocated node ports of an existing Service are kept. An existing Service not managed by the Iter8
+// resource is left unchanged until its adoption is confirmed (see adoptionForIter8).
+func (r *Iter8Reconciler) createOrUpdateService(iter8 *iter8v1alpha1.Iter8, service *corev1.Service) error {
+	// Get current state
+	found := &corev1.Service{}
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: service.Name, Namespace: service.Namespace}, found)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			r.Log.Info("Service not found, creating", "name", service.Name)
+			return r.Client.Create(context.TODO(), service)
+		}
+		return err
+	}
+	if !r.isManaged(iter8, found) {
+		r.Log.Info("Service already present and not managed by Iter8 resource", "name", service.Name)
+		return nil
+	}
+
+	ports := make([]corev1.ServicePort, len(service.Spec.Ports))
+	copy(ports, service.Spec.Ports)
+	if service.Spec.Type != corev1.ServiceTypeClusterIP {
+		for i := range ports {
+			if ports[i].NodePort != 0 {
+				continue
+			}
+			for _, p := range found.Spec.Ports {
+				if p.Port == ports[i].Port {
+					ports[i].NodePort = p.NodePort
+				}
+			}
+		}
+	}
+
+	// If changed, update
+	if found.Spec.Type == service.Spec.Type &&
+		equality.Semantic.DeepEqual(found.Spec.Ports, ports) &&
+		equality.Semantic.DeepEqual(found.Spec.Selector, service.Spec.Selector) &&
+		hasMetadata(found, service) {
+		r.Log.Info("Service already present", "name", service.Name)
+		return nil
+	}
+	r.Log.Info("Service changed, updating", "name", service.Name)
+	found.Spec.Type = service.Spec.Type
+	found.Spec.Ports = ports
+	found.Spec.Selector = service.Spec.Selector
+	if service.Spec.Type == corev1.ServiceTypeClusterIP {
+		// only valid for services exposed on nodes
+		found.Spec.ExternalTrafficPolicy = ""
+		found.Spec.HealthCheckNodePort = 0
+	}
+	mergeMetadata(found, service)
+	return r.Client.Update(context.TODO(), found)
+}
+
+// exposureForService creates or deletes the Ingress and OpenShift Route of a service, as specified
+func (r *Iter8Reconciler) exposureForService(iter8 *iter8v1alpha1.Iter8, svc *corev1.Service, spec *iter8v1alpha1.ServiceSpec) error {
+	app := svc.Name
+	if nil != spec && nil != spec.Ingress {
+		result := r.apply(iter8, r.ingressForService(iter8, svc, spec.Ingress))
+		if nil != result.Err {
+			return result.Err
+		}
+	} else {
+		err := r.deleteIfControlled(iter8, &networkingv1beta1.Ingress{}, app)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !r.routes {
+		if nil != spec && nil != spec.Route {
+			r.Log.Info("Routes are not supported by the cluster, not exposing service", "name", app)
+			r.Recorder.Event(iter8, corev1.EventTypeWarning, "RouteNotSupported", "Service "+app+" not exposed: Routes are not supported by the cluster")
+		}
+		return nil
+	}
+	if nil != spec && nil != spec.Route {
+		result := r.apply(iter8, r.routeForService(iter8, svc, spec.Route))
+		return result.Err
+	}
+	route := &unstructured.Unstructured{}
+	route.SetGroupVersionKind(routeGVK)
+	return r.deleteIfControlled(iter8, route, app)
+}
+
+func (r *Iter8Reconciler) ingressForService(iter8 *iter8v1alpha1.Iter8, svc *corev1.Service, spec *iter8v1alpha1.IngressSpec) *networkingv1beta1.Ingress {
+	path := "/"
+	if nil != spec.Path {
+		path = *spec.Path
+	}
+	pathType := networkingv1beta1.PathTypePrefix
+
+	ing := &networkingv1beta1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        svc.Name,
+			Namespace:   iter8.Namespace,
+			Annotations: spec.Annotations,
+		},
+		Spec: networkingv1beta1.IngressSpec{
+			IngressClassName: spec.IngressClassName,
+			TLS:              spec.TLS,
+			Rules: []networkingv1beta1.IngressRule{{
+				Host: spec.Host,
+				IngressRuleValue: networkingv1beta1.IngressRuleValue{
+					HTTP: &networkingv1beta1.HTTPIngressRuleValue{
+						Paths: []networkingv1beta1.HTTPIngressPath{{
+							Path:     path,
+							PathType: &pathType,
+							Backend: networkingv1beta1.IngressBackend{
+								ServiceName: svc.Name,
+								ServicePort: intstr.FromInt(int(svc.Spec.Ports[0].Port)),
+							},
+						}},
+					},
+				},
+			}},
+		},
+	}
+
+	setMetadata(iter8, ing, labelsForComponent(iter8, svc.Name))
+	r.override(iter8, ing)
+	return ing
+}
+
+func (r *Iter8Reconciler) routeForService(iter8 *iter8v1alpha1.Iter8, svc *corev1.Service, spec *iter8v1alpha1.RouteSpec) *unstructured.Unstructured {
+	routeSpec := map[string]interface{}{
+		"to": map[string]interface{}{
+			"kind":   "Service",
+			"name":   svc.Name,
+			"weight": int64(100),
+		},
+		"port": map[string]interface{}{
+			"targetPort": svc.Spec.Ports[0].Name,
+		},
+	}
+	if nil != spec.Host {
+		routeSpec["host"] = *spec.Host
+	}
+	if nil != spec.Termination {
+		routeSpec["tls"] = map[string]interface{}{
+			"termination": *spec.Termination,
+		}
+	}
+
+	route := &unstructured.Unstructured{Object: map[string]interface{}{"spec": routeSpec}}
+	route.SetGroupVersionKind(routeGVK)
+	route.SetName(svc.Name)
+	route.SetNamespace(iter8.Namespace)
+	route.SetAnnotations(spec.Annotations)
+
+	setMetadata(iter8, route, labelsForComponent(iter8, svc.Name))
+	r.override(iter8, route)
+	return route
+}
+
+// deleteIfControlled deletes the named object in the namespace of the Iter8 resource if it is controlled by it
+func (r *Iter8Reconciler) deleteIfControlled(iter8 *iter8v1alpha1.Iter8, obj runtime.Object, name string) error {
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: iter8.Namespace}, obj)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(accessor, iter8) {
+		return nil
+	}
+	r.Log.Info("Deleting object no longer needed", "kind", obj.GetObjectKind().GroupVersionKind().Kind, "name", name)
+	err = r.Client.Delete(context.TODO(), obj)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
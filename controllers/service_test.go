@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"testing"
+
+	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestSetServiceExposure(t *testing.T) {
+	metrics := intstr.FromString("metrics")
+	name := "web"
+
+	tests := []struct {
+		name           string
+		spec           *iter8v1alpha1.ServiceSpec
+		wantName       string
+		wantTargetPort intstr.IntOrString
+	}{{
+		name:           "defaults to the service port",
+		wantName:       "https",
+		wantTargetPort: intstr.FromInt(443),
+	}, {
+		name:           "configured target port",
+		spec:           &iter8v1alpha1.ServiceSpec{TargetPort: &metrics},
+		wantName:       "https",
+		wantTargetPort: metrics,
+	}, {
+		name:           "configured port name",
+		spec:           &iter8v1alpha1.ServiceSpec{PortName: &name},
+		wantName:       "web",
+		wantTargetPort: intstr.FromInt(443),
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &corev1.Service{Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 443}}}}
+			setServiceExposure(svc, tt.spec, "https")
+			port := svc.Spec.Ports[0]
+			if port.Name != tt.wantName || port.TargetPort != tt.wantTargetPort {
+				t.Errorf("port = %s -> %s, want %s -> %s", port.Name, port.TargetPort.String(), tt.wantName, tt.wantTargetPort.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensions "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	teardownInitialBackoff = 2 * time.Second
+	teardownMaxBackoff     = 5 * time.Minute
+)
+
+// teardownStep removes one cluster-scoped resource
+type teardownStep struct {
+	kind   string
+	name   string
+	object runtime.Object
+	// shared is set if the resource is shared by all Iter8 instances rather than owned by one
+	shared bool
+	// prepare, if set, is called before the resource is deleted
+	prepare func() error
+}
+
+// teardownSteps returns the cluster-scoped resources to be removed, in order
+func (r *Iter8Reconciler) teardownSteps(iter8 *iter8v1alpha1.Iter8, experiments []unstructured.Unstructured, others int) []teardownStep {
+	steps := []teardownStep{{
+		kind:   "ClusterRoleBinding",
+		name:   roleBindingName(iter8),
+		object: &rbacv1.ClusterRoleBinding{},
+	}, {
+		kind:   "ClusterRole",
+		name:   roleName(iter8),
+		object: &rbacv1.ClusterRole{},
+	}, {
+		// adopted from an installation not created by the operator
+		kind:   "ClusterRoleBinding",
+		name:   roleBindingDefaultName,
+		object: &rbacv1.ClusterRoleBinding{},
+	}, {
+		kind:   "ClusterRole",
+		name:   roleDefaultName,
+		object: &rbacv1.ClusterRole{},
+	}}
+
+	// the experiment roles are retained with the CustomResourceDefinition
+	if iter8v1alpha1.GetUninstallPolicy(iter8.Spec.Uninstall) == iter8v1alpha1.UninstallPolicyRetainCRD {
+		r.Log.Info("finalize retaining CustomResourceDefinition", "name", experimentCRDName)
+		return steps
+	}
+	if others > 0 {
+		// the CustomResourceDefinition is shared by all Iter8 instances
+		r.Log.Info("finalize retaining CustomResourceDefinition used by other Iter8 instances", "name", experimentCRDName, "instances", others)
+		return steps
+	}
+	steps = append(steps, teardownStep{
+		kind:   "ClusterRole",
+		name:   experimentEditorRoleName,
+		object: &rbacv1.ClusterRole{},
+		shared: true,
+	}, teardownStep{
+		kind:   "ClusterRole",
+		name:   experimentViewerRoleName,
+		object: &rbacv1.ClusterRole{},
+		shared: true,
+	})
+	return append(steps, teardownStep{
+		kind:   "CustomResourceDefinition",
+		name:   experimentCRDName,
+		object: &apiextensions.CustomResourceDefinition{},
+		shared: true,
+		prepare: func() error {
+			return r.archiveExperiments(iter8, experiments)
+		},
+	})
+}
+
+// teardown removes the cluster-scoped resources created for the Iter8 resource. Each step is attempted
+// independently; a step that fails is retried with exponential backoff. Progress is recorded in status.
+// Returns true when every step has succeeded; otherwise returns when teardown should next be attempted.
+func (r *Iter8Reconciler) teardown(iter8 *iter8v1alpha1.Iter8, experiments []unstructured.Unstructured, others int) (time.Duration, bool) {
+	original := iter8.Status.DeepCopy()
+	now := time.Now()
+	done := true
+	requeueAfter := teardownMaxBackoff
+
+	for _, step := range r.teardownSteps(iter8, experiments, others) {
+		status := teardownStatus(&iter8.Status, step.kind, step.name)
+		if status.Removed || status.Retained {
+			continue
+		}
+
+		if wait := teardownRetryAt(status).Sub(now); wait > 0 {
+			r.Log.Info("finalize waiting to retry", "kind", step.kind, "name", step.name, "after", wait)
+			if wait < requeueAfter {
+				requeueAfter = wait
+			}
+			done = false
+			continue
+		}
+
+		r.Log.Info("finalize deleting", "kind", step.kind, "name", step.name)
+		retained, err := r.teardownStep(iter8, step)
+		if err != nil {
+			r.Log.Error(err, "Unable to delete", "kind", step.kind, "name", step.name)
+			status.Attempts++
+			status.LastAttemptTime = &metav1.Time{Time: now}
+			status.LastError = err.Error()
+			if wait := teardownRetryAt(status).Sub(now); wait < requeueAfter {
+				requeueAfter = wait
+			}
+			done = false
+			continue
+		}
+		status.Removed = !retained
+		status.Retained = retained
+		status.LastError = ""
+	}
+
+	if !equality.Semantic.DeepEqual(original, &iter8.Status) {
+		err := r.Client.Status().Update(context.TODO(), iter8)
+		if err != nil {
+			r.Log.Error(err, "Unable to update Iter8 status")
+		}
+	}
+
+	if !done {
+		return requeueAfter, false
+	}
+	return 0, true
+}
+
+// teardownStep deletes a single resource. A resource that is not present is treated as removed.
+// A resource not labeled as owned by iter8, or as shared if a shared resource, is not deleted; returns true
+// if it was retained.
+func (r *Iter8Reconciler) teardownStep(iter8 *iter8v1alpha1.Iter8, step teardownStep) (bool, error) {
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: step.name}, step.object)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	accessor, err := meta.Accessor(step.object)
+	if err != nil {
+		return false, err
+	}
+	owned := isOwnedBy(iter8, accessor)
+	if step.shared {
+		owned = isManagedShared(accessor)
+	}
+	if !owned {
+		r.Log.Info("finalize not deleting resource created by another owner", "kind", step.kind, "name", step.name)
+		return true, nil
+	}
+
+	if nil != step.prepare {
+		err = step.prepare()
+		if err != nil {
+			return false, err
+		}
+	}
+
+	err = r.Client.Delete(context.TODO(), step.object)
+	if err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+	return false, nil
+}
+
+// teardownStatus returns the status of a teardown step, adding it if not already present
+func teardownStatus(status *iter8v1alpha1.Iter8Status, kind string, name string) *iter8v1alpha1.TeardownStepStatus {
+	for i := range status.Teardown {
+		if status.Teardown[i].Kind == kind && status.Teardown[i].Name == name {
+			return &status.Teardown[i]
+		}
+	}
+	status.Teardown = append(status.Teardown, iter8v1alpha1.TeardownStepStatus{Kind: kind, Name: name})
+	return &status.Teardown[len(status.Teardown)-1]
+}
+
+// teardownRetryAt returns when a failed step may next be attempted
+func teardownRetryAt(status *iter8v1alpha1.TeardownStepStatus) time.Time {
+	if nil == status.LastAttemptTime || status.Attempts == 0 {
+		return time.Time{}
+	}
+	backoff := teardownInitialBackoff
+	for i := int32(1); i < status.Attempts && backoff < teardownMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > teardownMaxBackoff {
+		backoff = teardownMaxBackoff
+	}
+	return status.LastAttemptTime.Add(backoff)
+}
@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	experimentCRDName        = "experiments.iter8.tools"
+	experimentPhaseCompleted = "Completed"
+)
+
+var experimentListGVK = schema.GroupVersionKind{Group: "iter8.tools", Version: "v1alpha2", Kind: "ExperimentList"}
+
+// listExperiments returns all experiments in the cluster; none if the CustomResourceDefinition is not installed
+func (r *Iter8Reconciler) listExperiments() ([]unstructured.Unstructured, error) {
+	experiments := &unstructured.UnstructuredList{}
+	experiments.SetGroupVersionKind(experimentListGVK)
+	err := r.Client.List(context.TODO(), experiments)
+	if err != nil {
+		if meta.IsNoMatchError(err) || errors.IsNotFound(err) {
+			return []unstructured.Unstructured{}, nil
+		}
+		return nil, err
+	}
+	return experiments.Items, nil
+}
+
+// activeExperiments returns the names of experiments that have not completed
+func activeExperiments(experiments []unstructured.Unstructured) []string {
+	active := []string{}
+	for _, experiment := range experiments {
+		phase, _, _ := unstructured.NestedString(experiment.Object, "status", "phase")
+		if phase != experimentPhaseCompleted {
+			active = append(active, experiment.GetNamespace()+"/"+experiment.GetName())
+		}
+	}
+	return active
+}
+
+// uninstallBlocked determines whether the uninstall policy prevents the experiments CustomResourceDefinition
+// from being deleted now. If so, an Event is recorded and the UninstallBlocked condition is set.
+func (r *Iter8Reconciler) uninstallBlocked(iter8 *iter8v1alpha1.Iter8, experiments []unstructured.Unstructured) bool {
+	if iter8v1alpha1.GetUninstallPolicy(iter8.Spec.Uninstall) != iter8v1alpha1.UninstallPolicyBlockIfExperimentsExist {
+		return false
+	}
+
+	active := activeExperiments(experiments)
+	if len(active) == 0 {
+		r.setCondition(iter8, iter8v1alpha1.Condition{
+			Type:   iter8v1alpha1.Iter8ConditionUninstallBlocked,
+			Status: corev1.ConditionFalse,
+		})
+		return false
+	}
+
+	message := fmt.Sprintf("Deletion blocked by uninstall policy %s: %d active experiment(s), including %s",
+		iter8v1alpha1.UninstallPolicyBlockIfExperimentsExist, len(active), active[0])
+	r.Log.Info("finalize blocked", "active experiments", len(active))
+	if nil != r.Recorder {
+		r.Recorder.Event(iter8, corev1.EventTypeWarning, iter8v1alpha1.Iter8ReasonActiveExperiments, message)
+	}
+	r.setCondition(iter8, iter8v1alpha1.Condition{
+		Type:    iter8v1alpha1.Iter8ConditionUninstallBlocked,
+		Status:  corev1.ConditionTrue,
+		Reason:  iter8v1alpha1.Iter8ReasonActiveExperiments,
+		Message: message,
+	})
+	return true
+}
+
+// archiveExperiments exports all experiments to the archive ConfigMap or Secret, if one is specified.
+// The archive is not owned by the Iter8 resource so that it is not deleted with it.
+func (r *Iter8Reconciler) archiveExperiments(iter8 *iter8v1alpha1.Iter8, experiments []unstructured.Unstructured) error {
+	if nil == iter8.Spec.Uninstall || nil == iter8.Spec.Uninstall.Archive || len(experiments) == 0 {
+		return nil
+	}
+	archive := *iter8.Spec.Uninstall.Archive
+
+	data := map[string]string{}
+	for _, experiment := range experiments {
+		experiment = *experiment.DeepCopy()
+		unstructured.RemoveNestedField(experiment.Object, "metadata", "managedFields")
+		unstructured.RemoveNestedField(experiment.Object, "metadata", "resourceVersion")
+		unstructured.RemoveNestedField(experiment.Object, "metadata", "uid")
+		raw, err := yaml.Marshal(experiment.Object)
+		if err != nil {
+			return err
+		}
+		data[experiment.GetNamespace()+"."+experiment.GetName()+".yaml"] = string(raw)
+	}
+
+	objectMeta := metav1.ObjectMeta{
+		Name:      iter8v1alpha1.GetArchiveName(archive),
+		Namespace: iter8v1alpha1.GetArchiveNamespace(archive, iter8.Namespace),
+	}
+	var obj, found runtime.Object
+	if iter8v1alpha1.GetArchiveKind(archive) == "Secret" {
+		obj = &corev1.Secret{ObjectMeta: objectMeta, StringData: data}
+		found = &corev1.Secret{}
+	} else {
+		obj = &corev1.ConfigMap{ObjectMeta: objectMeta, Data: data}
+		found = &corev1.ConfigMap{}
+	}
+
+	r.Log.Info("finalize archiving experiments", "kind", iter8v1alpha1.GetArchiveKind(archive), "name", objectMeta.Name, "namespace", objectMeta.Namespace, "count", len(data))
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Name: objectMeta.Name, Namespace: objectMeta.Namespace}, found)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return r.Client.Create(context.TODO(), obj)
+		}
+		return err
+	}
+	obj.(metav1.Object).SetResourceVersion(found.(metav1.Object).GetResourceVersion())
+	return r.Client.Update(context.TODO(), obj)
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+
+	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
+)
+
+// timeUnitScale is the number of milliseconds in each supported time unit
+var timeUnitScale = map[string]int{
+	"msec": 1,
+	"sec":  1000,
+}
+
+// normalizedUnits returns the units in which a metric with units is reported after normalization
+func normalizedUnits(units string, canonicalUnits string) string {
+	if _, ok := timeUnitScale[units]; ok && canonicalUnits != "" {
+		return canonicalUnits
+	}
+	return units
+}
+
+// ratioUnits returns the units of a ratio given the units of the numerator and denominator.
+// A ratio of values with the same units has no units; a ratio with a denominator with no units
+// has the units of the numerator. All other combinations are incompatible.
+func ratioUnits(numeratorUnits string, denominatorUnits string) (string, error) {
+	switch {
+	case denominatorUnits == "":
+		return numeratorUnits, nil
+	case numeratorUnits == denominatorUnits:
+		return "", nil
+	case numeratorUnits == "":
+		return "", fmt.Errorf("denominator units %s require units for the numerator", denominatorUnits)
+	default:
+		return "", fmt.Errorf("numerator units %s are incompatible with denominator units %s", numeratorUnits, denominatorUnits)
+	}
+}
+
+// convertQueryTemplate rewrites a query template reporting values in units to report values in canonicalUnits
+func convertQueryTemplate(template string, units string, canonicalUnits string) string {
+	from, ok := timeUnitScale[units]
+	if !ok {
+		return template
+	}
+	to, ok := timeUnitScale[canonicalUnits]
+	if !ok || from == to {
+		return template
+	}
+	if from > to {
+		return "(" + template + ") * " + strconv.Itoa(from/to)
+	}
+	return "(" + template + ") / " + strconv.Itoa(to/from)
+}
+
+// normalizeMetrics converts counter and gauge metrics with time units to canonicalUnits and sets the
+// units of each ratio metric from those of its numerator and denominator. Histogram metrics are
+// converted when their query templates are generated. Metrics are copied, not modified.
+func normalizeMetrics(metrics iter8v1alpha1.MetricsSpec, canonicalUnits string) iter8v1alpha1.MetricsSpec {
+	counterUnits := map[string]string{}
+	counterMetrics := make([]iter8v1alpha1.CounterMetricSpec, 0)
+	for _, metric := range *iter8v1alpha1.GetCounterMetrics(metrics) {
+		units := iter8v1alpha1.GetCounterMetricUnits(metric)
+		if normalized := normalizedUnits(units, canonicalUnits); normalized != units {
+			metric.QueryTemplate = convertQueryTemplate(metric.QueryTemplate, units, canonicalUnits)
+			metric.Units = &normalized
+		}
+		counterUnits[metric.Name] = iter8v1alpha1.GetCounterMetricUnits(metric)
+		counterMetrics = append(counterMetrics, metric)
+	}
+
+	ratioMetrics := make([]iter8v1alpha1.RatioMetricSpec, 0)
+	for _, metric := range *iter8v1alpha1.GetRatioMetrics(metrics) {
+		units, err := ratioUnits(counterUnits[metric.Numerator], counterUnits[metric.Denominator])
+		if err == nil && units != "" {
+			metric.Units = &units
+		}
+		ratioMetrics = append(ratioMetrics, metric)
+	}
+
+	gaugeMetrics := make([]iter8v1alpha1.GaugeMetricSpec, 0)
+	for _, metric := range *iter8v1alpha1.GetGaugeMetrics(metrics) {
+		units := iter8v1alpha1.GetGaugeMetricUnits(metric)
+		if normalized := normalizedUnits(units, canonicalUnits); normalized != units {
+			metric.QueryTemplate = convertQueryTemplate(metric.QueryTemplate, units, canonicalUnits)
+			metric.Units = &normalized
+		}
+		gaugeMetrics = append(gaugeMetrics, metric)
+	}
+
+	return iter8v1alpha1.MetricsSpec{
+		CanonicalUnits:   metrics.CanonicalUnits,
+		CounterMetrics:   &counterMetrics,
+		RatioMetrics:     &ratioMetrics,
+		HistogramMetrics: metrics.HistogramMetrics,
+		GaugeMetrics:     &gaugeMetrics,
+	}
+}
@@ -0,0 +1,273 @@
+package controllers
+
+import (
+	"testing"
+
+	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
+)
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestNormalizedUnits(t *testing.T) {
+	tests := []struct {
+		name           string
+		units          string
+		canonicalUnits string
+		want           string
+	}{
+		{name: "time units converted", units: "msec", canonicalUnits: "sec", want: "sec"},
+		{name: "already canonical", units: "sec", canonicalUnits: "sec", want: "sec"},
+		{name: "no canonical units", units: "msec", canonicalUnits: "", want: "msec"},
+		{name: "no units", units: "", canonicalUnits: "sec", want: ""},
+		{name: "other units", units: "bytes", canonicalUnits: "sec", want: "bytes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizedUnits(tt.units, tt.canonicalUnits); got != tt.want {
+				t.Errorf("normalizedUnits() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRatioUnits(t *testing.T) {
+	tests := []struct {
+		name             string
+		numeratorUnits   string
+		denominatorUnits string
+		want             string
+		wantErr          bool
+	}{
+		{name: "no units", want: ""},
+		{name: "denominator without units", numeratorUnits: "msec", want: "msec"},
+		{name: "same units", numeratorUnits: "sec", denominatorUnits: "sec", want: ""},
+		{name: "numerator without units", denominatorUnits: "sec", wantErr: true},
+		{name: "incompatible units", numeratorUnits: "msec", denominatorUnits: "sec", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ratioUnits(tt.numeratorUnits, tt.denominatorUnits)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ratioUnits() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ratioUnits() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertQueryTemplate(t *testing.T) {
+	tests := []struct {
+		name           string
+		units          string
+		canonicalUnits string
+		want           string
+	}{
+		{name: "msec to sec", units: "msec", canonicalUnits: "sec", want: "(q) / 1000"},
+		{name: "sec to msec", units: "sec", canonicalUnits: "msec", want: "(q) * 1000"},
+		{name: "same units", units: "sec", canonicalUnits: "sec", want: "q"},
+		{name: "no canonical units", units: "sec", canonicalUnits: "", want: "q"},
+		{name: "not time units", units: "bytes", canonicalUnits: "sec", want: "q"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := convertQueryTemplate("q", tt.units, tt.canonicalUnits); got != tt.want {
+				t.Errorf("convertQueryTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeMetrics(t *testing.T) {
+	metrics := iter8v1alpha1.MetricsSpec{
+		CounterMetrics: &[]iter8v1alpha1.CounterMetricSpec{
+			{Name: "duration", QueryTemplate: "d", Units: stringPtr("msec")},
+			{Name: "requests", QueryTemplate: "r"},
+		},
+		RatioMetrics: &[]iter8v1alpha1.RatioMetricSpec{
+			{Name: "mean_latency", Numerator: "duration", Denominator: "requests"},
+		},
+		GaugeMetrics: &[]iter8v1alpha1.GaugeMetricSpec{
+			{Name: "latency", QueryTemplate: "g", Units: stringPtr("sec")},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		canonicalUnits string
+		counterQuery   string
+		counterUnits   string
+		ratioUnits     string
+		gaugeQuery     string
+		gaugeUnits     string
+	}{{
+		name:           "sec",
+		canonicalUnits: "sec",
+		counterQuery:   "(d) / 1000",
+		counterUnits:   "sec",
+		ratioUnits:     "sec",
+		gaugeQuery:     "g",
+		gaugeUnits:     "sec",
+	}, {
+		name:           "msec",
+		canonicalUnits: "msec",
+		counterQuery:   "d",
+		counterUnits:   "msec",
+		ratioUnits:     "msec",
+		gaugeQuery:     "(g) * 1000",
+		gaugeUnits:     "msec",
+	}, {
+		name:         "no canonical units",
+		counterQuery: "d",
+		counterUnits: "msec",
+		ratioUnits:   "msec",
+		gaugeQuery:   "g",
+		gaugeUnits:   "sec",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := normalizeMetrics(metrics, tt.canonicalUnits)
+
+			counter := (*got.CounterMetrics)[0]
+			if counter.QueryTemplate != tt.counterQuery || iter8v1alpha1.GetCounterMetricUnits(counter) != tt.counterUnits {
+				t.Errorf("counter metric = %q in %q, want %q in %q", counter.QueryTemplate, iter8v1alpha1.GetCounterMetricUnits(counter), tt.counterQuery, tt.counterUnits)
+			}
+			ratio := (*got.RatioMetrics)[0]
+			if nil == ratio.Units || *ratio.Units != tt.ratioUnits {
+				t.Errorf("ratio metric units = %v, want %q", ratio.Units, tt.ratioUnits)
+			}
+			gauge := (*got.GaugeMetrics)[0]
+			if gauge.QueryTemplate != tt.gaugeQuery || iter8v1alpha1.GetGaugeMetricUnits(gauge) != tt.gaugeUnits {
+				t.Errorf("gauge metric = %q in %q, want %q in %q", gauge.QueryTemplate, iter8v1alpha1.GetGaugeMetricUnits(gauge), tt.gaugeQuery, tt.gaugeUnits)
+			}
+		})
+	}
+
+	// the metrics are copied, not modified
+	if (*metrics.CounterMetrics)[0].QueryTemplate != "d" || *(*metrics.CounterMetrics)[0].Units != "msec" {
+		t.Errorf("normalizeMetrics() modified its argument")
+	}
+}
+
+func TestNormalizeMetricsAfterMixerRewrite(t *testing.T) {
+	tests := []struct {
+		name           string
+		queryTemplate  string
+		units          string
+		canonicalUnits string
+		wantQuery      string
+		wantUnits      string
+	}{{
+		name:           "rewritten to sec, canonical msec",
+		queryTemplate:  "sum(istio_request_duration_milliseconds_sum{reporter='source'})",
+		units:          "msec",
+		canonicalUnits: "msec",
+		wantQuery:      "(sum(istio_request_duration_seconds_sum{reporter='source'})) * 1000",
+		wantUnits:      "msec",
+	}, {
+		name:           "rewritten to sec, canonical sec",
+		queryTemplate:  "sum(istio_request_duration_milliseconds_sum{reporter='source'})",
+		units:          "msec",
+		canonicalUnits: "sec",
+		wantQuery:      "sum(istio_request_duration_seconds_sum{reporter='source'})",
+		wantUnits:      "sec",
+	}, {
+		name:          "rewritten to sec, no canonical units",
+		queryTemplate: "sum(istio_request_duration_milliseconds_sum{reporter='source'})",
+		units:         "msec",
+		wantQuery:     "sum(istio_request_duration_seconds_sum{reporter='source'})",
+		wantUnits:     "sec",
+	}, {
+		name:           "not rewritten",
+		queryTemplate:  "sum(my_duration_milliseconds{job='envoy-stats'})",
+		units:          "msec",
+		canonicalUnits: "sec",
+		wantQuery:      "(sum(my_duration_milliseconds{job='istio-mesh'})) / 1000",
+		wantUnits:      "sec",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := iter8v1alpha1.MetricsSpec{
+				CounterMetrics: &[]iter8v1alpha1.CounterMetricSpec{
+					{Name: "duration", QueryTemplate: tt.queryTemplate, Units: stringPtr(tt.units)},
+				},
+				GaugeMetrics: &[]iter8v1alpha1.GaugeMetricSpec{
+					{Name: "latency", QueryTemplate: tt.queryTemplate, Units: stringPtr(tt.units)},
+				},
+			}
+			normalized := normalizeMetrics(rewriteForMixer(metrics), tt.canonicalUnits)
+
+			counter := (*normalized.CounterMetrics)[0]
+			if counter.QueryTemplate != tt.wantQuery || iter8v1alpha1.GetCounterMetricUnits(counter) != tt.wantUnits {
+				t.Errorf("counter metric = %q in %q, want %q in %q", counter.QueryTemplate, iter8v1alpha1.GetCounterMetricUnits(counter), tt.wantQuery, tt.wantUnits)
+			}
+			gauge := (*normalized.GaugeMetrics)[0]
+			if gauge.QueryTemplate != tt.wantQuery || iter8v1alpha1.GetGaugeMetricUnits(gauge) != tt.wantUnits {
+				t.Errorf("gauge metric = %q in %q, want %q in %q", gauge.QueryTemplate, iter8v1alpha1.GetGaugeMetricUnits(gauge), tt.wantQuery, tt.wantUnits)
+			}
+			if (*metrics.CounterMetrics)[0].QueryTemplate != tt.queryTemplate || (*metrics.GaugeMetrics)[0].QueryTemplate != tt.queryTemplate {
+				t.Errorf("rewriteForMixer() modified its argument")
+			}
+		})
+	}
+}
+
+func TestHistogramMetricsAfterMixerRewrite(t *testing.T) {
+	tests := []struct {
+		name           string
+		bucketMetric   string
+		canonicalUnits string
+		wantQuery      string
+		wantUnits      string
+	}{{
+		name:           "rewritten to sec, canonical msec",
+		bucketMetric:   "istio_request_duration_milliseconds_bucket",
+		canonicalUnits: "msec",
+		wantQuery:      "(histogram_quantile(0.95, sum(rate(istio_request_duration_seconds_bucket{reporter='source',job='istio-mesh'}[$interval])) by (le, $version_labels))) * 1000",
+		wantUnits:      "msec",
+	}, {
+		name:         "rewritten to sec, no canonical units",
+		bucketMetric: "istio_request_duration_milliseconds_bucket",
+		wantQuery:    "histogram_quantile(0.95, sum(rate(istio_request_duration_seconds_bucket{reporter='source',job='istio-mesh'}[$interval])) by (le, $version_labels))",
+		wantUnits:    "sec",
+	}, {
+		name:           "not rewritten",
+		bucketMetric:   "my_duration_milliseconds_bucket",
+		canonicalUnits: "msec",
+		wantQuery:      "histogram_quantile(0.95, sum(rate(my_duration_milliseconds_bucket{reporter='source',job='istio-mesh'}[$interval])) by (le, $version_labels))",
+		wantUnits:      "msec",
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			labelMatchers := "reporter='source',job='envoy-stats'"
+			metrics := iter8v1alpha1.MetricsSpec{
+				HistogramMetrics: &[]iter8v1alpha1.HistogramMetricSpec{{
+					Name:          "p95",
+					BucketMetric:  tt.bucketMetric,
+					Quantile:      "0.95",
+					LabelMatchers: &labelMatchers,
+					Units:         stringPtr("msec"),
+				}},
+			}
+			got := histogramMetricsWithQueryTemplates(*rewriteForMixer(metrics).HistogramMetrics, tt.canonicalUnits)[0]
+			if got.QueryTemplate != tt.wantQuery {
+				t.Errorf("query template = %q, want %q", got.QueryTemplate, tt.wantQuery)
+			}
+			if units := iter8v1alpha1.GetHistogramMetricUnits(got.HistogramMetricSpec); units != tt.wantUnits {
+				t.Errorf("units = %q, want %q", units, tt.wantUnits)
+			}
+			if original := (*metrics.HistogramMetrics)[0]; original.BucketMetric != tt.bucketMetric || *original.LabelMatchers != labelMatchers {
+				t.Errorf("rewriteForMixer() modified its argument")
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// Paths at which MetricsValidator is served
+const (
+	ValidateIter8Path  = "/validate-iter8-iter8-tools-v1alpha1-iter8"
+	ValidateMetricPath = "/validate-iter8-iter8-tools-v1alpha1-metric"
+)
+
+// +kubebuilder:webhook:path=/validate-iter8-iter8-tools-v1alpha1-iter8,mutating=false,failurePolicy=fail,groups=iter8.iter8.tools,resources=iter8s,verbs=create;update,versions=v1alpha1,name=viter8.iter8.tools
+// +kubebuilder:webhook:path=/validate-iter8-iter8-tools-v1alpha1-metric,mutating=false,failurePolicy=fail,groups=iter8.iter8.tools,resources=metrics,verbs=create;update,versions=v1alpha1,name=vmetric.iter8.tools
+
+// MetricsValidator rejects Iter8 and Metric resources that define invalid metrics. Whether ratio metrics refer
+// to known counter metrics depends on the metrics defined elsewhere, so is only checked when reconciling.
+type MetricsValidator struct {
+	decoder *admission.Decoder
+}
+
+// Handle validates the metrics defined inline by an Iter8 resource or by a Metric resource
+func (v *MetricsValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	var errs []string
+	switch req.Kind.Kind {
+	case "Iter8":
+		iter8 := &iter8v1alpha1.Iter8{}
+		if err := v.decoder.Decode(req, iter8); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		errs = admissionErrors(iter8.Spec.Metrics)
+	case "Metric":
+		metric := &iter8v1alpha1.Metric{}
+		if err := v.decoder.Decode(req, metric); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if err := checkMetricKind(metric.Spec); err != nil {
+			errs = []string{err.Error()}
+		} else {
+			errs = admissionErrors(metricsSpecOf(metric.Spec))
+		}
+	default:
+		return admission.Errored(http.StatusBadRequest, fmt.Errorf("unexpected kind %s", req.Kind.Kind))
+	}
+
+	if len(errs) > 0 {
+		return admission.Denied(strings.Join(errs, "; "))
+	}
+	return admission.Allowed("")
+}
+
+// InjectDecoder injects the decoder
+func (v *MetricsValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// admissionErrors returns the problems with metrics that can be found without the metrics defined elsewhere
+func admissionErrors(metrics iter8v1alpha1.MetricsSpec) []string {
+	_, errs := validMetrics(iter8v1alpha1.MetricsSpec{
+		CounterMetrics:   metrics.CounterMetrics,
+		HistogramMetrics: metrics.HistogramMetrics,
+		GaugeMetrics:     metrics.GaugeMetrics,
+	}, nil, "")
+	for _, metric := range *iter8v1alpha1.GetRatioMetrics(metrics) {
+		if metric.Name == "" {
+			errs = append(errs, "ratio metric has no name")
+		}
+	}
+	return errs
+}
+
+// metricsSpecOf returns a MetricsSpec with the metric defined by a Metric resource
+func metricsSpecOf(metric iter8v1alpha1.MetricSpec) iter8v1alpha1.MetricsSpec {
+	metrics := iter8v1alpha1.MetricsSpec{}
+	switch {
+	case nil != metric.Counter:
+		metrics.CounterMetrics = &[]iter8v1alpha1.CounterMetricSpec{*metric.Counter}
+	case nil != metric.Ratio:
+		metrics.RatioMetrics = &[]iter8v1alpha1.RatioMetricSpec{*metric.Ratio}
+	case nil != metric.Histogram:
+		metrics.HistogramMetrics = &[]iter8v1alpha1.HistogramMetricSpec{*metric.Histogram}
+	case nil != metric.Gauge:
+		metrics.GaugeMetrics = &[]iter8v1alpha1.GaugeMetricSpec{*metric.Gauge}
+	}
+	return metrics
+}
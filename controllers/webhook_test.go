@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestMetricsValidator(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = iter8v1alpha1.AddToScheme(scheme)
+	decoder, _ := admission.NewDecoder(scheme)
+	v := &MetricsValidator{}
+	_ = v.InjectDecoder(decoder)
+
+	iter8 := func(metrics iter8v1alpha1.MetricsSpec) runtime.Object {
+		return &iter8v1alpha1.Iter8{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "iter8.iter8.tools/v1alpha1", Kind: "Iter8"},
+			ObjectMeta: metav1.ObjectMeta{Name: "iter8", Namespace: "iter8"},
+			Spec:       iter8v1alpha1.Iter8Spec{Metrics: metrics},
+		}
+	}
+	metric := func(spec iter8v1alpha1.MetricSpec) runtime.Object {
+		return &iter8v1alpha1.Metric{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "iter8.iter8.tools/v1alpha1", Kind: "Metric"},
+			ObjectMeta: metav1.ObjectMeta{Name: "metric", Namespace: "apps"},
+			Spec:       spec,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		kind    string
+		obj     runtime.Object
+		allowed bool
+	}{{
+		name: "valid Iter8",
+		kind: "Iter8",
+		obj: iter8(iter8v1alpha1.MetricsSpec{
+			CounterMetrics: &[]iter8v1alpha1.CounterMetricSpec{{Name: "requests", QueryTemplate: testQueryTemplate}},
+			// refers to a counter metric defined elsewhere
+			RatioMetrics: &[]iter8v1alpha1.RatioMetricSpec{{Name: "error_rate", Numerator: "errors", Denominator: "requests"}},
+		}),
+		allowed: true,
+	}, {
+		name: "Iter8 with invalid query template",
+		kind: "Iter8",
+		obj: iter8(iter8v1alpha1.MetricsSpec{
+			GaugeMetrics: &[]iter8v1alpha1.GaugeMetricSpec{{Name: "cpu", QueryTemplate: "rate(cpu[$interval])"}},
+		}),
+	}, {
+		name:    "valid Metric",
+		kind:    "Metric",
+		obj:     metric(iter8v1alpha1.MetricSpec{Counter: &iter8v1alpha1.CounterMetricSpec{Name: "errors", QueryTemplate: testQueryTemplate}}),
+		allowed: true,
+	}, {
+		name: "Metric with invalid histogram",
+		kind: "Metric",
+		obj:  metric(iter8v1alpha1.MetricSpec{Histogram: &iter8v1alpha1.HistogramMetricSpec{Name: "p95", BucketMetric: "b", Quantile: "95"}}),
+	}, {
+		name: "Metric with two metrics",
+		kind: "Metric",
+		obj: metric(iter8v1alpha1.MetricSpec{
+			Counter: &iter8v1alpha1.CounterMetricSpec{Name: "errors", QueryTemplate: testQueryTemplate},
+			Gauge:   &iter8v1alpha1.GaugeMetricSpec{Name: "cpu", QueryTemplate: testQueryTemplate},
+		}),
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := json.Marshal(tt.obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := admission.Request{AdmissionRequest: admissionv1beta1.AdmissionRequest{
+				Kind:   metav1.GroupVersionKind{Group: "iter8.iter8.tools", Version: "v1alpha1", Kind: tt.kind},
+				Object: runtime.RawExtension{Raw: raw},
+			}}
+			resp := v.Handle(context.TODO(), req)
+			if resp.Allowed != tt.allowed {
+				t.Errorf("Handle() allowed = %v, want %v: %v", resp.Allowed, tt.allowed, resp.Result)
+			}
+		})
+	}
+}
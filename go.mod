@@ -3,9 +3,11 @@ module github.com/iter8-tools/iter8-operator
 go 1.13
 
 require (
+	github.com/evanphx/json-patch v4.5.0+incompatible
 	github.com/go-logr/logr v0.1.0
 	github.com/onsi/ginkgo v1.12.1
 	github.com/onsi/gomega v1.10.1
+	github.com/prometheus/prometheus v0.0.0-20200626085723-c448ada63d83
 	gopkg.in/yaml.v2 v2.3.0
 	istio.io/pkg v0.0.0-20200908160754-55ca040b8d7a
 	k8s.io/api v0.18.6
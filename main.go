@@ -26,6 +26,7 @@ import (
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	iter8v1alpha1 "github.com/iter8-tools/iter8-operator/api/v1alpha1"
 	"github.com/iter8-tools/iter8-operator/controllers"
@@ -71,13 +72,20 @@ func main() {
 	}
 
 	if err = (&controllers.Iter8Reconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("Iter8"),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("Iter8"),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("iter8-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Iter8")
 		os.Exit(1)
 	}
+	// the webhook server requires a serving certificate; see config/default to deploy one with cert-manager
+	if os.Getenv("ENABLE_WEBHOOKS") == "true" {
+		server := mgr.GetWebhookServer()
+		server.Register(controllers.ValidateIter8Path, &webhook.Admission{Handler: &controllers.MetricsValidator{}})
+		server.Register(controllers.ValidateMetricPath, &webhook.Admission{Handler: &controllers.MetricsValidator{}})
+	}
 	// +kubebuilder:scaffold:builder
 
 	setupLog.Info("starting manager")